@@ -0,0 +1,103 @@
+// Copyright 2024 Peter Schwaller (peter@schwaller.org)
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"schwaller.org/go-brown-sports/pkg"
+	"strings"
+	"time"
+)
+
+// recordArchive Save everything we parsed, past and future, so there's a record of who worked
+// each game after the spreadsheet is cleared.  A problem with the archive shouldn't stop the sync.
+func recordArchive(cfg *pkg.Config, logger *slog.Logger, spreadsheetEvents []pkg.SportingEvent, currentTime time.Time) {
+	archive, err := pkg.OpenArchive(cfg.GetArchivePath())
+	if err != nil {
+		logger.Error("Unable to archive events", "error", err)
+
+		return
+	}
+	defer archive.Close()
+
+	err = archive.Record(spreadsheetEvents, currentTime)
+	if err != nil {
+		logger.Error("Unable to archive events", "error", err)
+	}
+}
+
+func runArchiveQuery(args []string) int {
+	const dateLayout = "2006-01-02"
+
+	flags, options := newFlagSet("archive")
+	options.idsOptional = true
+	worker := flags.String("worker", "", "only list events this worker was assigned to")
+	sport := flags.String("sport", "", "only list events whose sport contains this text")
+	from := flags.String("from", "", "only list events on or after this date (YYYY-MM-DD)")
+	to := flags.String("to", "", "only list events on or before this date (YYYY-MM-DD)")
+
+	if exitCode, ok := options.parse(flags, args); !ok {
+		return exitCode
+	}
+
+	filter := pkg.ArchiveFilter{Worker: *worker, Sport: *sport}
+	timeZone := options.config.GetTimeZone()
+
+	var err error
+
+	if *from != "" {
+		filter.From, err = time.ParseInLocation(dateLayout, *from, timeZone)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -from date %s: %v\n", *from, err)
+
+			return exitUsage
+		}
+	}
+
+	if *to != "" {
+		filter.To, err = time.ParseInLocation(dateLayout, *to, timeZone)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -to date %s: %v\n", *to, err)
+
+			return exitUsage
+		}
+		// The filter's To is exclusive, but people expect "-to" to include the whole day.
+		filter.To = filter.To.AddDate(0, 0, 1)
+	}
+
+	archive, err := pkg.OpenArchive(options.config.GetArchivePath())
+	if err != nil {
+		return failure("Unable to open archive", "error", err)
+	}
+	defer archive.Close()
+
+	archivedEvents, err := archive.Query(filter)
+	if err != nil {
+		return failure("Unable to query archive", "error", err)
+	}
+
+	if options.output == outputJSON {
+		if archivedEvents == nil {
+			archivedEvents = []pkg.ArchivedEvent{}
+		}
+
+		return printJSON(archivedEvents)
+	}
+
+	for _, archivedEvent := range archivedEvents {
+		// The archive keeps each event's offset, so away games show their local time.
+		fmt.Printf("%s  %s\n", archivedEvent.Datetime.Format("Mon Jan 2, 2006 3:04pm"), archivedEvent.Sport)
+
+		for _, assignment := range archivedEvent.Assignments {
+			if *worker != "" && !strings.EqualFold(assignment.Worker, *worker) {
+				continue
+			}
+
+			fmt.Printf("    %s: %s\n", assignment.Role, assignment.Worker)
+		}
+	}
+
+	return exitOK
+}
@@ -0,0 +1,309 @@
+// Copyright 2024 Peter Schwaller (peter@schwaller.org)
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"schwaller.org/go-brown-sports/pkg"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	authLogin  = "login"
+	authStatus = "status"
+	authLogout = "logout"
+	authScopes = "scopes"
+)
+
+// runAuth auth [login|status|logout|scopes] [flags].  With no action, show the status of the saved token.
+func runAuth(args []string) int {
+	action := authStatus
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		action, args = args[0], args[1:]
+	}
+
+	switch action {
+	case authLogin:
+		return runAuthLogin(args)
+	case authStatus:
+		return runAuthStatus(args)
+	case authLogout:
+		return runAuthLogout(args)
+	case authScopes:
+		return runAuthScopes(args)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown auth action %q, expected %s, %s, %s or %s\n",
+			action, authLogin, authStatus, authLogout, authScopes)
+
+		return exitUsage
+	}
+}
+
+func runAuthLogin(args []string) int {
+	flags, options := newFlagSet("auth " + authLogin)
+	options.idsOptional = true
+	flow := flags.String("flow", "", "how to authorize: loopback (defaults to auth.flow in the config)")
+	profiles := flags.String("profile", "", profileUsage)
+
+	if exitCode, ok := options.parse(flags, args); !ok {
+		return exitCode
+	}
+
+	if !usesOAuth(options.config, authLogin) {
+		return exitUsage
+	}
+
+	scopes, err := pkg.ScopesFor(parseProfiles(options.config, *profiles)...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+
+		return exitUsage
+	}
+
+	config, err := pkg.LoadOAuthConfig(options.config, scopes)
+	if err != nil {
+		return failure("Unable to read client credentials", "error", err)
+	}
+
+	if *flow == "" {
+		*flow = options.config.GetAuthConfiguration().Flow
+	}
+
+	if err = pkg.Login(context.Background(), options.config, config, *flow); err != nil {
+		return failure("Unable to log in", "error", err)
+	}
+
+	return reportTokenStatus(options, pkg.CheckToken(context.Background(), options.config, config))
+}
+
+func runAuthStatus(args []string) int {
+	flags, options := newFlagSet("auth " + authStatus)
+	options.idsOptional = true
+	profiles := flags.String("profile", "", profileUsage)
+
+	if exitCode, ok := options.parse(flags, args); !ok {
+		return exitCode
+	}
+
+	status := pkg.CheckAuth(context.Background(), options.config, parseProfiles(options.config, *profiles))
+
+	return reportTokenStatus(options, status)
+}
+
+// scopesReport The output of auth scopes -output json.
+type scopesReport struct {
+	Method   string          `json:"method"`
+	Scopes   []string        `json:"scopes"`
+	Profiles []profileScopes `json:"profiles"`
+}
+
+type profileScopes struct {
+	Name       string   `json:"name"`
+	Configured bool     `json:"configured"` // Needed by the features turned on in the config
+	Scopes     []string `json:"scopes"`
+	Missing    []string `json:"missing"`
+}
+
+// runAuthScopes Show the scopes the token has, and which profiles they cover.  Exits with
+// exitFindings if a profile the config needs is missing scopes.
+func runAuthScopes(args []string) int {
+	flags, options := newFlagSet("auth " + authScopes)
+	options.idsOptional = true
+	if exitCode, ok := options.parse(flags, args); !ok {
+		return exitCode
+	}
+
+	configured := pkg.ConfiguredProfiles(options.config)
+
+	status := pkg.CheckAuth(context.Background(), options.config, configured)
+	if !status.Valid || status.Scopes == nil {
+		return failure("Unable to get the token's scopes", "error", status.Error)
+	}
+
+	report := scopesReport{Method: status.Method, Scopes: status.Scopes}
+
+	for _, name := range sortedProfileNames() {
+		scopes, _ := pkg.ScopesFor(name)
+		report.Profiles = append(report.Profiles, profileScopes{
+			Name:       name,
+			Configured: slices.Contains(configured, name),
+			Scopes:     scopes,
+			Missing:    append([]string{}, pkg.MissingScopes(status.Scopes, scopes)...),
+		})
+	}
+
+	if options.output == outputJSON {
+		if exitCode := printJSON(report); exitCode != exitOK {
+			return exitCode
+		}
+	} else {
+		printScopesReport(report)
+	}
+
+	if len(status.MissingScopes) > 0 {
+		return exitFindings
+	}
+
+	return exitOK
+}
+
+func printScopesReport(report scopesReport) {
+	fmt.Println("Granted scopes:")
+
+	for _, scope := range report.Scopes {
+		fmt.Printf("    %s\n", scope)
+	}
+
+	fmt.Println("Profiles:")
+
+	for _, profile := range report.Profiles {
+		configured := ""
+		if profile.Configured {
+			configured = " (needed by the config)"
+		}
+
+		if len(profile.Missing) == 0 {
+			fmt.Printf("    %-9s  granted%s\n", profile.Name, configured)
+		} else {
+			fmt.Printf("    %-9s  missing %s%s\n", profile.Name, strings.Join(profile.Missing, ", "), configured)
+		}
+	}
+}
+
+const profileUsage = "comma-separated auth profiles: readonly, calendar, writeback or watch " +
+	"(defaults to what the config needs)"
+
+// parseProfiles With no profiles, use the ones for everything turned on in the config.
+func parseProfiles(cfg *pkg.Config, value string) []string {
+	if value == "" {
+		return pkg.ConfiguredProfiles(cfg)
+	}
+
+	var profiles []string
+
+	for _, profile := range strings.Split(value, ",") {
+		if profile = strings.TrimSpace(profile); profile != "" {
+			profiles = append(profiles, profile)
+		}
+	}
+
+	return profiles
+}
+
+func sortedProfileNames() []string {
+	names := make([]string, 0, len(pkg.Profiles))
+	for name := range pkg.Profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func runAuthLogout(args []string) int {
+	flags, options := newFlagSet("auth " + authLogout)
+	options.idsOptional = true
+	revoke := flags.Bool("revoke", false, "ask Google to revoke the token before deleting it")
+
+	if exitCode, ok := options.parse(flags, args); !ok {
+		return exitCode
+	}
+
+	if !usesOAuth(options.config, authLogout) {
+		return exitUsage
+	}
+
+	if err := pkg.Logout(context.Background(), options.config, *revoke); err != nil {
+		return failure("Unable to log out", "error", err)
+	}
+
+	if options.output == outputJSON {
+		return printJSON(map[string]any{"path": options.config.GetTokenPath(), "revoked": *revoke})
+	}
+
+	fmt.Printf("Deleted %s\n", options.config.GetTokenPath())
+
+	return exitOK
+}
+
+// usesOAuth Service accounts and default credentials don't have a token to log in or out of.
+func usesOAuth(cfg *pkg.Config, action string) bool {
+	method := cfg.GetAuthConfiguration().Method
+	if method != pkg.AuthMethodOAuth {
+		fmt.Fprintf(os.Stderr, "auth %s is only for the %s auth method, but auth.method is %s\n",
+			action, pkg.AuthMethodOAuth, method)
+
+		return false
+	}
+
+	return true
+}
+
+// reportTokenStatus A token that can't be used is a failure, so scripts can check before a sync.
+func reportTokenStatus(options *commonOptions, status pkg.TokenStatus) int {
+	if options.output == outputJSON {
+		if exitCode := printJSON(status); exitCode != exitOK {
+			return exitCode
+		}
+	} else {
+		printTokenStatus(status)
+	}
+
+	if !status.Valid || len(status.MissingScopes) > 0 {
+		return exitFailure
+	}
+
+	return exitOK
+}
+
+func printTokenStatus(status pkg.TokenStatus) {
+	if status.Method != pkg.AuthMethodOAuth {
+		printCredentialsStatus(status)
+
+		return
+	}
+
+	switch {
+	case !status.Exists:
+		fmt.Printf("No token at %s.  Run go-brown-sports auth login.\n", status.Path)
+	case !status.Valid:
+		fmt.Printf("The token at %s can't be used: %s\n", status.Path, status.Error)
+	default:
+		fmt.Printf("The token at %s is valid until %s.\n", status.Path, status.Expiry.Local().Format(time.RFC1123))
+
+		if !status.HasRefreshToken {
+			fmt.Println("It has no refresh token, so you'll need to log in again when it expires.")
+		}
+
+		if len(status.MissingScopes) > 0 {
+			fmt.Printf("It's missing %s.  Run go-brown-sports auth login to add them.\n",
+				strings.Join(status.MissingScopes, ", "))
+		}
+	}
+}
+
+func printCredentialsStatus(status pkg.TokenStatus) {
+	credentials := "The application default credentials"
+
+	switch {
+	case status.Method == pkg.AuthMethodServiceAccount && status.Path != "":
+		credentials = "The service account key at " + status.Path
+	case status.Method == pkg.AuthMethodServiceAccount:
+		credentials = "The service account key"
+	}
+
+	switch {
+	case status.Valid && len(status.MissingScopes) > 0:
+		fmt.Printf("%s can be used, but Google didn't grant %s.\n", credentials, strings.Join(status.MissingScopes, ", "))
+	case status.Valid:
+		fmt.Printf("%s can be used.\n", credentials)
+	default:
+		fmt.Printf("%s can't be used: %s\n", credentials, status.Error)
+	}
+}
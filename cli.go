@@ -0,0 +1,278 @@
+// Copyright 2024 Peter Schwaller (peter@schwaller.org)
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"schwaller.org/go-brown-sports/pkg"
+	"slices"
+	"strings"
+)
+
+// Exit codes, so scripts can tell what happened.
+const (
+	exitOK       = 0
+	exitFailure  = 1 // Something went wrong
+	exitUsage    = 2 // Bad command line, or a mistake in the config
+	exitFindings = 3 // The command worked and found something to act on: problems, or changes to make
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+// The first command is the default.
+var commands = []command{
+	{"sync", "Sync the spreadsheet into the calendar", runSync},
+	{"plan", "Show what sync would change, without changing anything", runPlan},
+	{"validate", "Check the spreadsheet for problems, without touching the calendar", runValidate},
+	{"export", "Write the events on the spreadsheet as JSON, CSV or iCalendar", runExport},
+	{"workers", "List the worker directory and names that can't be resolved", runWorkers},
+	{"auth", "Manage the Google authorization token", runAuth},
+	{"serve", "Keep running, syncing on a schedule", runServe},
+	{"remind", "Send reminders for upcoming events", runRemind},
+	{"archive", "Search the historical archive", runArchiveQuery},
+	{"history", "Show the audit log of calendar changes", runHistory},
+}
+
+// shutdownTracing Flush any spans that haven't been exported.  Set up by commonOptions.parse.
+var shutdownTracing = func() {}
+
+func main() {
+	os.Exit(runCommand(os.Args[1:]))
+}
+
+func runCommand(args []string) int {
+	defer shutdownTracing()
+
+	// With no command, we do what we've always done: sync the calendar.
+	name := commands[0].name
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		printUsage(os.Stdout)
+
+		return exitOK
+	}
+
+	for _, command := range commands {
+		if command.name == name {
+			return command.run(args)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown command %q.\n\n", name)
+	printUsage(os.Stderr)
+
+	return exitUsage
+}
+
+func printUsage(file *os.File) {
+	fmt.Fprintf(file, "Usage: go-brown-sports [command] [flags]\n\nCommands:\n")
+
+	for _, command := range commands {
+		fmt.Fprintf(file, "  %-9s %s\n", command.name, command.summary)
+	}
+
+	fmt.Fprintf(file, "\nRun go-brown-sports <command> -h for the command's flags.\n")
+}
+
+// commonOptions The flags every command accepts.
+type commonOptions struct {
+	configPath string
+	output     string
+	outputs    []string
+	tenant     string
+
+	// Set before parse for commands that don't read the spreadsheet or calendar.
+	idsOptional bool
+
+	// Set before parse for commands that run for every tenant unless -tenant picks one.
+	allTenants bool
+
+	// Read by parse.  root is the whole config, and config is the first tenant's (or root, without tenants).
+	root    *pkg.Config
+	config  *pkg.Config
+	tenants []*pkg.Config
+}
+
+// newFlagSet The first output format is the default.
+func newFlagSet(name string, outputs ...string) (*flag.FlagSet, *commonOptions) {
+	if len(outputs) == 0 {
+		outputs = []string{outputText, outputJSON}
+	}
+
+	options := &commonOptions{outputs: outputs}
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.StringVar(&options.configPath, "config", "config.yaml", "path to the config file")
+	flags.StringVar(&options.output, "output", outputs[0], "output format: "+strings.Join(outputs, ", "))
+	flags.StringVar(&options.tenant, "tenant", "", "the tenant to use, if the config has tenants")
+
+	return flags, options
+}
+
+// parse Parse the command line, then read the config and set up logging and tracing.  If ok is
+// false, the command should exit right away with the exit code.
+func (options *commonOptions) parse(flags *flag.FlagSet, args []string) (exitCode int, ok bool) {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+
+		return exitUsage, false
+	}
+
+	if flags.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Unexpected arguments: %s\n", strings.Join(flags.Args(), " "))
+
+		return exitUsage, false
+	}
+
+	if !slices.Contains(options.outputs, options.output) {
+		fmt.Fprintf(os.Stderr, "Invalid -output %q, expected %s\n", options.output, strings.Join(options.outputs, ", "))
+
+		return exitUsage, false
+	}
+
+	// The default config file is optional, since everything can come from environment variables.
+	configPathSet := false
+
+	flags.Visit(func(flag *flag.Flag) {
+		configPathSet = configPathSet || flag.Name == "config"
+	})
+
+	config, err := pkg.LoadConfig(options.configPath, configPathSet)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+
+		return exitUsage, false
+	}
+
+	if err = config.Validate(!options.idsOptional); err != nil {
+		fmt.Fprintf(os.Stderr, "The config has problems:\n%v\n", indent(err.Error()))
+
+		return exitUsage, false
+	}
+
+	options.tenants, err = options.selectTenants(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+
+		return exitUsage, false
+	}
+
+	options.root = config
+	options.config = options.tenants[0]
+
+	for _, tenant := range options.tenants {
+		if err = tenant.MakeTenantDirectory(); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+
+			return exitFailure, false
+		}
+	}
+
+	// Logging and tracing are for the whole process, so they come from the top level of the config.
+	if err = pkg.ConfigureLogging(config.GetLoggingConfiguration()); err != nil {
+		return failure("Unable to configure logging", "error", err), false
+	}
+
+	shutdown, err := pkg.ConfigureTracing(context.Background(), config.GetTracingConfiguration())
+	if err != nil {
+		return failure("Unable to configure tracing", "error", err), false
+	}
+
+	shutdownTracing = func() {
+		if err := shutdown(context.Background()); err != nil {
+			slog.Error("Unable to export the remaining spans", "error", err)
+		}
+	}
+
+	return exitOK, true
+}
+
+// selectTenants The tenants the command runs for: the one picked with -tenant, or all of them if the
+// command can run them all.  Without tenants, that's just the config.
+func (options *commonOptions) selectTenants(config *pkg.Config) ([]*pkg.Config, error) {
+	tenants := config.GetTenants()
+
+	if options.tenant == "" {
+		if len(tenants) > 1 && !options.allTenants {
+			return nil, fmt.Errorf("the config has tenants %s, pick one with -tenant", tenantNames(tenants))
+		}
+
+		return tenants, nil
+	}
+
+	for _, tenant := range tenants {
+		if tenant.GetTenantName() == options.tenant {
+			return []*pkg.Config{tenant}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("the config has no tenant %q", options.tenant)
+}
+
+func tenantNames(tenants []*pkg.Config) string {
+	names := make([]string, 0, len(tenants))
+	for _, tenant := range tenants {
+		names = append(names, tenant.GetTenantName())
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// tenantArgs Add the tenant to a log message's arguments, if there are tenants.
+func tenantArgs(cfg *pkg.Config, args ...any) []any {
+	if name := cfg.GetTenantName(); name != "" {
+		return append([]any{"tenant", name}, args...)
+	}
+
+	return args
+}
+
+// indent Indent every line of a multi-line message.
+func indent(message string) string {
+	return "  " + strings.ReplaceAll(message, "\n", "\n  ")
+}
+
+// failure Log an error, and return the exit code for it.
+func failure(message string, args ...any) int {
+	slog.Error(message, args...)
+
+	return exitFailure
+}
+
+// printJSON Write a command's results to stdout.
+func printJSON(value any) int {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(value); err != nil {
+		return failure("Unable to write output", "error", err)
+	}
+
+	return exitOK
+}
+
+// quietLogger For commands whose output already reports what would otherwise be logged.
+func quietLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
@@ -0,0 +1,40 @@
+// Copyright 2024 Peter Schwaller (peter@schwaller.org)
+
+package main
+
+import (
+	"log/slog"
+	"schwaller.org/go-brown-sports/pkg"
+)
+
+// sendDigests Email each affected worker a summary of how this run changed their assignments.
+func sendDigests(cfg *pkg.Config, logger *slog.Logger, changes []pkg.SyncChange, workerDirectory map[string]string) {
+	digestConfig := cfg.GetDigestConfiguration()
+	if !digestConfig.Enabled {
+		return
+	}
+
+	mailer, err := pkg.NewDigestMailer(cfg.GetSMTPConfiguration(), digestConfig)
+	if err != nil {
+		logger.Error("Unable to send digests", "error", err)
+
+		return
+	}
+
+	sentCount := 0
+
+	for _, digest := range pkg.BuildDigests(changes, workerDirectory) {
+		if mailer.IsOptedOut(digest) {
+			continue
+		}
+
+		if err = mailer.Send(digest); err != nil {
+			logger.Error("Unable to send digest", "worker", digest.Worker, "error", err)
+
+			continue
+		}
+		sentCount++
+	}
+
+	logger.Info("Digests sent", "count", sentCount)
+}
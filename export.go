@@ -0,0 +1,68 @@
+// Copyright 2024 Peter Schwaller (peter@schwaller.org)
+
+package main
+
+import (
+	"os"
+	"schwaller.org/go-brown-sports/pkg"
+	"slices"
+	"strings"
+	"time"
+)
+
+func runExport(args []string) int {
+	flags, options := newFlagSet("export", pkg.ExportJSON, pkg.ExportCSV, pkg.ExportICS)
+	future := flags.Bool("future", false, "only export events that haven't started yet")
+	worker := flags.String("worker", "", "only export events this worker is assigned to")
+
+	if exitCode, ok := options.parse(flags, args); !ok {
+		return exitCode
+	}
+
+	ctx, client, err := pkg.AccessGoogleClient(options.config, pkg.ProfileReadOnly)
+	if err != nil {
+		return failure("Unable to create Google client", "error", err)
+	}
+
+	spreadsheet, err := loadSpreadsheet(ctx, options.config, quietLogger(), client)
+	if err != nil {
+		return failure("Unable to read the spreadsheet", "error", err)
+	}
+
+	currentTime := time.Now()
+
+	var sportingEvents []pkg.SportingEvent
+
+	for _, sportingEvent := range spreadsheet.events {
+		if *future && !sportingEvent.Datetime.After(currentTime) {
+			continue
+		}
+
+		if *worker != "" && !isAssigned(sportingEvent, *worker) {
+			continue
+		}
+
+		sportingEvents = append(sportingEvents, sportingEvent)
+	}
+
+	slices.SortStableFunc(sportingEvents, func(a, b pkg.SportingEvent) int {
+		return a.Datetime.Compare(b.Datetime)
+	})
+
+	if err = pkg.ExportEvents(os.Stdout, options.output, sportingEvents); err != nil {
+		return failure("Unable to export events", "error", err)
+	}
+
+	return exitOK
+}
+
+// isAssigned Match the worker by the name on the spreadsheet or by email address.
+func isAssigned(sportingEvent pkg.SportingEvent, worker string) bool {
+	for _, assignment := range sportingEvent.Assignments {
+		if strings.EqualFold(assignment.Worker, worker) || strings.EqualFold(assignment.Email, worker) {
+			return true
+		}
+	}
+
+	return false
+}
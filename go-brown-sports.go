@@ -3,101 +3,491 @@
 package main
 
 import (
-	mapset "github.com/deckarep/golang-set"
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/option"
-	"log"
+	"google.golang.org/api/sheets/v4"
+	"log/slog"
+	"net/http"
 	"schwaller.org/go-brown-sports/pkg"
 	"time"
 )
 
-func main() {
+// runSync Sync every tenant in turn, unless -tenant picks one.  A tenant whose sync fails doesn't stop
+// the others.
+func runSync(args []string) int {
+	flags, options := newFlagSet("sync")
+	force := flags.Bool("force", false, "sync even if the spreadsheet hasn't changed since the last sync")
+
+	options.allTenants = true
+
+	if exitCode, ok := options.parse(flags, args); !ok {
+		return exitCode
+	}
+
+	exitCode := exitOK
+	reports := make([]syncReport, 0, len(options.tenants))
+
+	for _, cfg := range options.tenants {
+		result, err := syncOnce(cfg, *force)
+		if err != nil {
+			exitCode = failure("Sync failed", tenantArgs(cfg, "run_id", result.runID, "error", err)...)
+
+			continue
+		}
+
+		if anyFailed(result.changes) {
+			exitCode = exitFailure
+		}
+
+		reports = append(reports, newSyncReport(cfg, result))
+	}
+
+	if options.output == outputJSON && len(reports) > 0 {
+		// With tenants, there's a report for each one that synced.
+		var output any = reports
+		if len(options.root.Tenants) == 0 {
+			output = reports[0]
+		}
+
+		if printExitCode := printJSON(output); printExitCode != exitOK {
+			return printExitCode
+		}
+	}
+
+	return exitCode
+}
+
+// syncReport The output of sync -output json.
+type syncReport struct {
+	Tenant  string           `json:"tenant,omitempty"`
+	RunID   string           `json:"runId"`
+	Skipped bool             `json:"skipped"`
+	Changes []pkg.AuditEntry `json:"changes"`
+}
+
+func newSyncReport(cfg *pkg.Config, result syncResult) syncReport {
+	report := syncReport{
+		Tenant:  cfg.GetTenantName(),
+		RunID:   result.runID,
+		Skipped: result.skipped,
+		Changes: []pkg.AuditEntry{},
+	}
+
+	timestamp := time.Now()
+	for _, change := range result.changes {
+		report.Changes = append(report.Changes, pkg.NewAuditEntry(result.runID, change, timestamp))
+	}
+
+	return report
+}
+
+// syncResult What a single sync run did.
+type syncResult struct {
+	runID       string
+	skipped     bool // The spreadsheet hadn't changed, so there was nothing to do
+	changes     []pkg.SyncChange
+	nextEvent   time.Time // When the next event on the spreadsheet starts.  Zero if there are none.
+	diagnostics *pkg.Diagnostics
+	upcoming    []pkg.SportingEvent
+}
+
+// syncOnce Sync the spreadsheet into the calendar, unless the spreadsheet hasn't changed since the
+// last successful sync and we aren't forcing it.
+func syncOnce(cfg *pkg.Config, force bool) (result syncResult, err error) {
+	result.runID = pkg.NewRunID()
+	logger := cfg.GetLogger().With("run_id", result.runID)
+
+	ctx, span := pkg.StartSpan(context.Background(), "sync",
+		attribute.String("tenant", cfg.GetTenantName()), attribute.String("run_id", result.runID))
+	defer func() { pkg.EndSpan(span, err) }()
+
+	// Make sure a sync from cron and a sync from the daemon can't both change the calendar at once.
+	runLock, err := pkg.AcquireRunLock(cfg.GetLockPath())
+	if err != nil {
+		return result, err
+	}
+	defer runLock.Release()
+
 	// Set up access to the Google APIs we're using
-	ctx, client, err := pkg.AccessGoogleClient()
+	_, client, err := pkg.AccessGoogleClient(cfg, pkg.SyncProfiles(cfg)...)
 	if err != nil {
-		log.Fatalf("Unable to create Google client: %v", err)
+		return result, fmt.Errorf("unable to create Google client: %w", err)
+	}
+
+	// If the spreadsheets and settings are the same as last time, the calendars are already up to date,
+	// unless someone changed our events by hand.  Refreshing the calendar caches tells us that.
+	syncState := pkg.LoadSyncState(cfg.GetSyncStatePath(), logger)
+	sourcesKey := pkg.SourcesKey(cfg.GetSpreadsheetSources())
+	configHash := pkg.SyncConfigHash(cfg)
+
+	revision, revisionErr := getSpreadsheetRevision(ctx, cfg, client)
+	if revisionErr != nil {
+		logger.Warn("Unable to check for spreadsheet changes, syncing anyway", "error", revisionErr)
+	}
+
+	calendars := loadCalendars(ctx, logger, client, cfg.GetCalendarRoutes())
+
+	if revisionErr == nil && !force && syncState.Unchanged(sourcesKey, configHash, revision) && calendarsUnchanged(calendars) {
+		logger.Info("Spreadsheet and calendars unchanged since the last sync, skipping",
+			"last_success", syncState.LastSuccess.Format(time.RFC3339))
+
+		// Keep the new sync tokens, so the next run only asks for what changed after this one.
+		for _, routeCalendar := range calendars {
+			if err = routeCalendar.cache.Save(time.Now()); err != nil {
+				logger.Error("Unable to save the calendar cache", "calendar", routeCalendar.route.Name, "error", err)
+			}
+		}
+
+		result.skipped = true
+		span.SetAttributes(attribute.Bool("skipped", true))
+
+		return result, nil
 	}
 
 	// We're going to create a series of maps using datetime+sport as the key, and
 	// a SportingEvent struct as the value.
 
-	// Capture the current time and pass it to both the GetCalendarMaps and
-	// GetSpreadsheetMaps.  This eliminates a race condition when the
+	// Capture the current time and pass it to both the GetFutureEventMaps and
+	// FilterFutureEvents.  This eliminates a race condition when the
 	// program is run right around an event start time.
 	currentTime := time.Now()
 
-	// Access the calendar and generate the maps
-	calendarService, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	spreadsheet, err := loadSpreadsheet(ctx, cfg, logger, client)
 	if err != nil {
-		log.Fatalf("Unable to retrieve Calendar client: %v", err)
+		return result, err
 	}
 
-	calendarFutureEvents, calendarFutureEventIds := pkg.GetCalendarMaps(calendarService, currentTime)
+	result.diagnostics = spreadsheet.diagnostics
+
+	recordArchive(cfg, logger, spreadsheet.events, currentTime)
+
+	spreadsheetFutureEvents := pkg.FilterFutureEvents(spreadsheet.events, currentTime)
+	for _, sportingEvent := range spreadsheetFutureEvents {
+		result.upcoming = append(result.upcoming, sportingEvent)
+
+		if result.nextEvent.IsZero() || sportingEvent.Datetime.Before(result.nextEvent) {
+			result.nextEvent = sportingEvent.Datetime
+		}
+	}
+
+	// Each calendar is synced on its own, so a problem with one doesn't hold up the others.
+	var externalChanges []pkg.SyncChange
+
+	var calendarErrors []error
 
-	// Access the spreadsheet and generate the map
-	sheetService, err := pkg.AccessSpreadsheet(ctx, client)
+	for _, routeCalendar := range calendars {
+		changes, err := syncCalendar(ctx, logger, routeCalendar, spreadsheetFutureEvents, currentTime)
+		if err != nil {
+			logger.Error("Unable to sync calendar", "calendar", routeCalendar.route.Name, "error", err)
+			calendarErrors = append(calendarErrors, fmt.Errorf("calendar %s: %w", routeCalendar.route.Name, err))
+		}
+
+		result.changes = append(result.changes, changes...)
+		externalChanges = append(externalChanges, routeCalendar.externalChanges...)
+	}
+
+	err = pkg.NewAuditLog(cfg.GetAuditLogPath(), result.runID).Record(append(externalChanges, result.changes...))
 	if err != nil {
-		log.Fatalf("Unable to access spreadsheet: %v", err)
+		logger.Error("Unable to record the audit log", "error", err)
 	}
 
-	spreadsheetFutureEvents := pkg.GetSpreadsheetMap(sheetService, currentTime)
+	// The other calendars get copies of the main calendar's events, so people only hear about each
+	// change once.
+	mainChanges := changesTo(result.changes, pkg.MainCalendar)
+	sendDigests(cfg, logger, mainChanges, spreadsheet.workerDirectory)
+	sendNotifications(cfg, logger, result.runID, mainChanges)
 
-	// Now that we have all the maps, let's sync the spreadsheet info into the calendar.
-	synchronizeCalendar(spreadsheetFutureEvents, calendarFutureEvents, calendarFutureEventIds, calendarService)
+	if cfg.GetWritebackConfiguration().Enabled {
+		writeBack(ctx, cfg, logger, spreadsheet, result.changes, currentTime)
+	}
+
+	// Only remember the revision if everything made it onto the calendar, so failures get retried.  It's
+	// the revision read before the sync, so edits made while we ran aren't taken as seen.  Our own
+	// writeback makes a newer revision too, which costs one more run, but that run writes nothing back.
+	if revision.Version != "" && !anyFailed(result.changes) && len(calendarErrors) == 0 {
+		if err = syncState.RecordSuccess(sourcesKey, configHash, revision, currentTime); err != nil {
+			logger.Error("Unable to record the sync state", "error", err)
+		}
+	}
+
+	return result, errors.Join(calendarErrors...)
 }
 
-func synchronizeCalendar(
+// routeCalendar A calendar to sync, with its cache brought up to date.  err is set if it couldn't be.
+type routeCalendar struct {
+	route           pkg.CalendarRoute
+	service         *calendar.Service
+	cache           *pkg.CalendarCache
+	externalChanges []pkg.SyncChange // Changes people made to our events by hand
+	err             error
+}
+
+// loadCalendars Only fetch what changed on each calendar since the last run.  Along the way, we find
+// out about any changes people made to our events by hand.  A calendar that can't be loaded doesn't
+// stop the others.
+func loadCalendars(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *http.Client,
+	routes []pkg.CalendarRoute) []routeCalendar {
+	calendars := make([]routeCalendar, 0, len(routes))
+
+	for _, route := range routes {
+		service, cache, externalChanges, err := loadCalendar(ctx, route, logger.With("calendar", route.Name), client)
+		calendars = append(calendars, routeCalendar{
+			route:           route,
+			service:         service,
+			cache:           cache,
+			externalChanges: withCalendar(externalChanges, route.Name),
+			err:             err,
+		})
+	}
+
+	return calendars
+}
+
+// calendarsUnchanged Whether every calendar is as we left it.  A full listing can't tell, so it
+// doesn't count.
+func calendarsUnchanged(calendars []routeCalendar) bool {
+	for _, routeCalendar := range calendars {
+		if routeCalendar.err != nil || len(routeCalendar.externalChanges) > 0 || routeCalendar.cache.Relisted() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// syncCalendar Bring one calendar up to date with the events routed to it, and return the changes
+// made.
+func syncCalendar(
+	ctx context.Context,
+	logger *slog.Logger,
+	routeCalendar routeCalendar,
 	spreadsheetFutureEvents map[string]pkg.SportingEvent,
-	calendarFutureEvents map[string]pkg.SportingEvent,
-	calendarFutureEventIds map[string]string,
-	calendarService *calendar.Service) {
-	var err error
+	currentTime time.Time) ([]pkg.SyncChange, error) {
+	if routeCalendar.err != nil {
+		return nil, routeCalendar.err
+	}
 
-	// Create sets.  This makes determining what's missing and extra simple and straightforward.
-	calendarSet := mapset.NewSetFromSlice(pkg.GetKeysFromSportingEventMap(calendarFutureEvents))
-	spreadsheetSet := mapset.NewSetFromSlice(pkg.GetKeysFromSportingEventMap(spreadsheetFutureEvents))
+	route := routeCalendar.route
+	logger = logger.With("calendar", route.Name)
 
-	missingInCalendar := spreadsheetSet.Difference(calendarSet)
-	for key := range missingInCalendar.Iter() {
-		sportingEvent := spreadsheetFutureEvents[key.(string)]
-		err = pkg.CreateCalendarEvent(calendarService, pkg.GetCalendarID(), sportingEvent)
+	calendarFutureEvents, calendarFutureEventIds := routeCalendar.cache.GetFutureEventMaps(currentTime)
 
-		if err != nil {
-			log.Printf("Error creating calendar event for %s: %v", key, err)
+	// Now that we have all the maps, let's sync the spreadsheet info into the calendar.
+	plan := pkg.PlanChanges(ctx, route.FilterEvents(spreadsheetFutureEvents), calendarFutureEvents,
+		calendarFutureEventIds)
+	changes := synchronizeCalendar(ctx, logger, plan, routeCalendar.service, route.CalendarID, routeCalendar.cache)
+
+	if err := routeCalendar.cache.Save(currentTime); err != nil {
+		logger.Error("Unable to save the calendar cache", "error", err)
+	}
+
+	return withCalendar(changes, route.Name), nil
+}
+
+// withCalendar Mark the changes as made to the named calendar.
+func withCalendar(changes []pkg.SyncChange, name string) []pkg.SyncChange {
+	for index := range changes {
+		changes[index].Calendar = name
+	}
+
+	return changes
+}
+
+// changesTo The changes made to the named calendar.
+func changesTo(changes []pkg.SyncChange, name string) []pkg.SyncChange {
+	var filtered []pkg.SyncChange
+
+	for _, change := range changes {
+		if change.Calendar == name {
+			filtered = append(filtered, change)
 		}
 	}
 
-	extraInCalendar := calendarSet.Difference(spreadsheetSet)
-	for key := range extraInCalendar.Iter() {
-		eventID := calendarFutureEventIds[key.(string)]
-		err = calendarService.Events.Delete(pkg.GetCalendarID(), eventID).Do()
+	return filtered
+}
+
+// spreadsheetData What we read from the spreadsheet.
+type spreadsheetData struct {
+	sheetService    *sheets.Service
+	workers         []pkg.Worker
+	workerDirectory map[string]string
+	events          []pkg.SportingEvent // Past and future
+	diagnostics     *pkg.Diagnostics
+}
+
+// loadSpreadsheet Read the worker directory and every month tab.
+func loadSpreadsheet(
+	ctx context.Context,
+	cfg *pkg.Config,
+	logger *slog.Logger,
+	client *http.Client) (spreadsheetData, error) {
+	data := spreadsheetData{diagnostics: pkg.NewDiagnostics(logger)}
+
+	var err error
+
+	data.sheetService, err = pkg.AccessSpreadsheet(ctx, client)
+	if err != nil {
+		return data, fmt.Errorf("unable to access spreadsheet: %w", err)
+	}
+
+	data.workers, err = pkg.LoadWorkers(ctx, cfg, data.sheetService, data.diagnostics)
+	if err != nil {
+		return data, err
+	}
+
+	data.workerDirectory = pkg.BuildWorkerDirectory(data.workers, data.diagnostics)
+
+	data.events, err = pkg.GetSpreadsheetEvents(ctx, cfg, data.sheetService, data.workerDirectory, data.diagnostics)
+	if err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
+
+// loadCalendar Bring the route's calendar cache up to date, and return the changes to our events
+// that were made outside of this program.
+func loadCalendar(
+	ctx context.Context,
+	route pkg.CalendarRoute,
+	logger *slog.Logger,
+	client *http.Client) (*calendar.Service, *pkg.CalendarCache, []pkg.SyncChange, error) {
+	calendarService, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("unable to retrieve Calendar client: %w", err)
+	}
+
+	calendarCache := pkg.LoadCalendarCache(route.CachePath, route.CalendarID, logger)
+
+	externalChanges, err := calendarCache.Refresh(ctx, calendarService)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	for _, change := range externalChanges {
+		logger.Warn("Calendar event was changed outside of this program",
+			"event_key", change.Key, "calendar_event_id", change.CalendarEventID)
+	}
+
+	return calendarService, calendarCache, externalChanges, nil
+}
 
+// writeBack Put each row's status and problems into its spreadsheet.  A problem with the writeback
+// shouldn't stop the sync.
+func writeBack(
+	ctx context.Context,
+	cfg *pkg.Config,
+	logger *slog.Logger,
+	spreadsheet spreadsheetData,
+	changes []pkg.SyncChange,
+	currentTime time.Time) {
+	for _, source := range cfg.GetSpreadsheetSources() {
+		result, err := pkg.WriteBack(ctx, spreadsheet.sheetService, source, cfg.GetWritebackConfiguration(),
+			spreadsheet.events, changes, spreadsheet.diagnostics, currentTime)
 		if err != nil {
-			log.Printf("Error deleting %s: %v", key.(string), err)
+			logger.Error("Unable to write the sync status to the spreadsheet", "spreadsheet", source.Name, "error", err)
+
+			continue
+		}
+
+		if result.Changed() {
+			logger.Info("Wrote the sync status to the spreadsheet",
+				"spreadsheet", source.Name, "statuses", result.Statuses, "notes", result.Notes)
 		}
 	}
+}
 
-	matchingKeys := calendarSet.Intersect(spreadsheetSet)
-	updateCount := 0
+func getSpreadsheetRevision(
+	ctx context.Context,
+	cfg *pkg.Config,
+	client *http.Client) (pkg.SpreadsheetRevision, error) {
+	driveService, err := drive.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return pkg.SpreadsheetRevision{}, err
+	}
 
-	for key := range matchingKeys.Iter() {
-		keyString := key.(string)
-		if calendarFutureEvents[keyString].IsMostlyEqual(spreadsheetFutureEvents[keyString]) {
-			continue
+	return pkg.GetSpreadsheetsRevision(ctx, driveService, cfg.GetSpreadsheetSources())
+}
+
+func anyFailed(changes []pkg.SyncChange) bool {
+	for _, change := range changes {
+		if change.Err != nil {
+			return true
 		}
-		// If we're here, there must have been a change.  Update the calendar entry.
-		eventID := calendarFutureEventIds[keyString]
-		err = pkg.UpdateCalendarEvent(calendarService, pkg.GetCalendarID(), eventID, spreadsheetFutureEvents[keyString])
+	}
 
-		if err != nil {
-			log.Printf("Error updating %s: %v", keyString, err)
+	return false
+}
+
+// synchronizeCalendar Make the planned changes to the calendar.  A change that fails is returned
+// with its error, and doesn't stop the others.
+func synchronizeCalendar(
+	ctx context.Context,
+	logger *slog.Logger,
+	plan []pkg.SyncChange,
+	calendarService *calendar.Service,
+	calendarID string,
+	calendarCache *pkg.CalendarCache) []pkg.SyncChange {
+	changes := make([]pkg.SyncChange, 0, len(plan))
+
+	for _, change := range plan {
+		logger := logger.With("event_key", change.Key)
+
+		switch change.Action {
+		case pkg.SyncCreate:
+			event, err := pkg.CreateCalendarEvent(ctx, calendarService, calendarID, change.After)
+			if err != nil {
+				logger.Error("Unable to create calendar event", "error", err)
+			} else {
+				change.CalendarEventID = event.Id
+				calendarCache.Remember(event)
+				logger.Info("Created calendar event", "calendar_event_id", event.Id)
+			}
+
+			change.Err = err
+		case pkg.SyncDelete:
+			err := pkg.DeleteCalendarEvent(
+				ctx, calendarService, calendarID, change.CalendarEventID, change.Key)
+			if err != nil {
+				logger.Error("Unable to delete calendar event",
+					"calendar_event_id", change.CalendarEventID, "error", err)
+			} else {
+				calendarCache.Forget(change.CalendarEventID)
+				logger.Info("Deleted calendar event", "calendar_event_id", change.CalendarEventID)
+			}
+
+			change.Err = err
+		case pkg.SyncUpdate:
+			event, err := pkg.UpdateCalendarEvent(
+				ctx, calendarService, calendarID, change.CalendarEventID, change.After)
+			if err != nil {
+				logger.Error("Unable to update calendar event",
+					"calendar_event_id", change.CalendarEventID, "error", err)
+			} else {
+				calendarCache.Remember(event)
+				logger.Info("Updated calendar event", "calendar_event_id", change.CalendarEventID)
+			}
+
+			change.Err = err
 		}
-		updateCount++
+
+		changes = append(changes, change)
 	}
 
-	log.Printf(
-		"Missing: %d, Extra: %d, Updated: %d\n",
-		missingInCalendar.Cardinality(),
-		extraInCalendar.Cardinality(),
-		updateCount)
+	status := pkg.NewRunStatus("", time.Time{}, time.Time{}, changes)
+	logger.Info("Calendar synchronized",
+		"missing", status.Missing,
+		"extra", status.Extra,
+		"updated", status.Updated,
+		"failed", status.Failed)
+
+	return changes
 }
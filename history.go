@@ -0,0 +1,60 @@
+// Copyright 2024 Peter Schwaller (peter@schwaller.org)
+
+package main
+
+import (
+	"fmt"
+	"schwaller.org/go-brown-sports/pkg"
+)
+
+func runHistory(args []string) int {
+	flags, options := newFlagSet("history")
+	options.idsOptional = true
+	event := flags.String("event", "", "only show changes to events whose key contains this text")
+	worker := flags.String("worker", "", "only show changes that added or removed this worker")
+
+	if exitCode, ok := options.parse(flags, args); !ok {
+		return exitCode
+	}
+
+	entries, err := pkg.ReadAuditLog(options.config.GetAuditLogPath(), pkg.AuditFilter{Event: *event, Worker: *worker})
+	if err != nil {
+		return failure("Unable to read audit log", "error", err)
+	}
+
+	if options.output == outputJSON {
+		if entries == nil {
+			entries = []pkg.AuditEntry{}
+		}
+
+		return printJSON(entries)
+	}
+
+	for _, entry := range entries {
+		fmt.Printf("%s  %-8s  %s  (run %s)\n",
+			entry.Timestamp.Local().Format("2006-01-02 15:04:05"), entry.Action, eventLabel(entry), entry.RunID)
+		printFieldChanges(entry)
+	}
+
+	return exitOK
+}
+
+// eventLabel The event's key, and which calendar it's on unless that's the main calendar.
+func eventLabel(entry pkg.AuditEntry) string {
+	if entry.Calendar == "" || entry.Calendar == pkg.MainCalendar {
+		return entry.EventKey
+	}
+
+	return fmt.Sprintf("%s  [calendar %s]", entry.EventKey, entry.Calendar)
+}
+
+// printFieldChanges Show what an audit entry changed, or would change.
+func printFieldChanges(entry pkg.AuditEntry) {
+	for _, change := range entry.Changes {
+		fmt.Printf("    %s: %q -> %q\n", change.Field, change.Before, change.After)
+	}
+
+	if entry.Error != "" {
+		fmt.Printf("    FAILED: %s\n", entry.Error)
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2024 Peter Schwaller (peter@schwaller.org)
+
+package main
+
+import (
+	"log/slog"
+	"schwaller.org/go-brown-sports/pkg"
+	"time"
+)
+
+// sendNotifications Post one message per configured webhook summarizing the changes from this run.
+func sendNotifications(cfg *pkg.Config, logger *slog.Logger, runID string, changes []pkg.SyncChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	notification := pkg.NewRunNotification(runID, changes, time.Now())
+
+	for _, config := range cfg.GetNotifierConfigurations() {
+		notifier, err := pkg.NewNotifier(config)
+		if err != nil {
+			logger.Error("Unable to notify", "notifier", config.Type, "error", err)
+
+			continue
+		}
+
+		if err = notifier.Notify(notification); err != nil {
+			logger.Error("Unable to notify", "notifier", config.Type, "error", err)
+		}
+	}
+}
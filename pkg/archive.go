@@ -0,0 +1,193 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"strings"
+	"time"
+)
+
+// The archive keeps a permanent record of every event we have parsed from the spreadsheet.
+// The spreadsheet tabs are cleared at the end of each season, and the sync ignores past events,
+// so this is the only place to find out who actually worked a game.
+
+var archiveEventsBucket = []byte("events")
+
+type Archive struct {
+	db *bolt.DB
+}
+
+type ArchivedEvent struct {
+	Datetime    time.Time            `json:"datetime"`
+	Sport       string               `json:"sport"`
+	Assignments []ArchivedAssignment `json:"assignments"`
+	FirstSeen   time.Time            `json:"firstSeen"`
+	LastSeen    time.Time            `json:"lastSeen"`
+}
+
+type ArchivedAssignment struct {
+	Role      string    `json:"role"`
+	Worker    string    `json:"worker"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// ArchiveFilter Zero values match everything.  To is exclusive.
+type ArchiveFilter struct {
+	Worker string
+	Sport  string
+	From   time.Time
+	To     time.Time
+}
+
+func OpenArchive(path string) (*Archive, error) {
+	// Wait a bit rather than failing immediately if another run has the archive open.
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open archive %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(archiveEventsBucket)
+
+		return err
+	})
+	if err != nil {
+		db.Close()
+
+		return nil, fmt.Errorf("unable to initialize archive %s: %w", path, err)
+	}
+
+	return &Archive{db: db}, nil
+}
+
+func (archive *Archive) Close() error {
+	return archive.db.Close()
+}
+
+// Record Add or refresh the sporting events in the archive.  Events and assignments that have been
+// seen before keep their FirstSeen time and have their LastSeen time moved to seenTime.
+func (archive *Archive) Record(sportingEvents []SportingEvent, seenTime time.Time) error {
+	return archive.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(archiveEventsBucket)
+
+		for _, sportingEvent := range sportingEvents {
+			// Rows with unparseable dates end up with a zero time.  They aren't worth keeping.
+			if sportingEvent.Datetime.IsZero() {
+				continue
+			}
+
+			key := archiveKey(sportingEvent.Datetime, sportingEvent.Sport)
+
+			archivedEvent := ArchivedEvent{
+				Datetime:  sportingEvent.Datetime,
+				Sport:     sportingEvent.Sport,
+				FirstSeen: seenTime,
+			}
+
+			if existing := bucket.Get(key); existing != nil {
+				if err := json.Unmarshal(existing, &archivedEvent); err != nil {
+					return fmt.Errorf("corrupt archive entry %s: %w", key, err)
+				}
+			}
+
+			archivedEvent.LastSeen = seenTime
+			archivedEvent.mergeAssignments(sportingEvent.Assignments, seenTime)
+
+			value, err := json.Marshal(archivedEvent)
+			if err != nil {
+				return err
+			}
+
+			if err = bucket.Put(key, value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (archivedEvent *ArchivedEvent) mergeAssignments(assignments []Assignment, seenTime time.Time) {
+	for _, assignment := range assignments {
+		found := false
+
+		for index := range archivedEvent.Assignments {
+			archived := &archivedEvent.Assignments[index]
+			if archived.Role == assignment.Role && archived.Worker == assignment.Worker {
+				archived.LastSeen = seenTime
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			archivedEvent.Assignments = append(archivedEvent.Assignments, ArchivedAssignment{
+				Role:      assignment.Role,
+				Worker:    assignment.Worker,
+				FirstSeen: seenTime,
+				LastSeen:  seenTime,
+			})
+		}
+	}
+}
+
+// Query Get the archived events that match the filter, in date order.
+func (archive *Archive) Query(filter ArchiveFilter) ([]ArchivedEvent, error) {
+	var archivedEvents []ArchivedEvent
+
+	err := archive.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(archiveEventsBucket).Cursor()
+
+		// Keys start with the UTC datetime, so we can seek straight to the start of the range.
+		var key, value []byte
+		if filter.From.IsZero() {
+			key, value = cursor.First()
+		} else {
+			key, value = cursor.Seek([]byte(filter.From.UTC().Format(time.RFC3339)))
+		}
+
+		for ; key != nil; key, value = cursor.Next() {
+			var archivedEvent ArchivedEvent
+			if err := json.Unmarshal(value, &archivedEvent); err != nil {
+				return fmt.Errorf("corrupt archive entry %s: %w", key, err)
+			}
+
+			if !filter.To.IsZero() && !archivedEvent.Datetime.Before(filter.To) {
+				break
+			}
+
+			if filter.matches(archivedEvent) {
+				archivedEvents = append(archivedEvents, archivedEvent)
+			}
+		}
+
+		return nil
+	})
+
+	return archivedEvents, err
+}
+
+func (filter ArchiveFilter) matches(archivedEvent ArchivedEvent) bool {
+	if filter.Sport != "" && !strings.Contains(strings.ToLower(archivedEvent.Sport), strings.ToLower(filter.Sport)) {
+		return false
+	}
+
+	if filter.Worker == "" {
+		return true
+	}
+
+	for _, assignment := range archivedEvent.Assignments {
+		if strings.EqualFold(assignment.Worker, filter.Worker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func archiveKey(datetime time.Time, sport string) []byte {
+	return []byte(fmt.Sprintf("%s @ %s", datetime.UTC().Format(time.RFC3339), sport))
+}
@@ -0,0 +1,149 @@
+package pkg
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// The audit log is a JSON lines file with one entry for every change the sync makes to the calendar.
+// It answers the question "why did my assignment disappear?"
+
+type AuditEntry struct {
+	Timestamp       time.Time     `json:"timestamp"`
+	RunID           string        `json:"runId"`
+	Action          SyncAction    `json:"action"`
+	EventKey        string        `json:"eventKey"`
+	Calendar        string        `json:"calendar,omitempty"` // Empty for entries from before there were routes
+	CalendarEventID string        `json:"calendarEventId,omitempty"`
+	Changes         []FieldChange `json:"changes"`
+	Error           string        `json:"error,omitempty"`
+}
+
+// AuditFilter Zero values match everything.
+type AuditFilter struct {
+	Event  string
+	Worker string
+}
+
+type AuditLog struct {
+	path  string
+	runID string
+}
+
+func NewAuditLog(path string, runID string) *AuditLog {
+	return &AuditLog{path: path, runID: runID}
+}
+
+func NewAuditEntry(runID string, change SyncChange, timestamp time.Time) AuditEntry {
+	entry := AuditEntry{
+		Timestamp:       timestamp,
+		RunID:           runID,
+		Action:          change.Action,
+		EventKey:        change.Key,
+		Calendar:        change.Calendar,
+		CalendarEventID: change.CalendarEventID,
+		Changes:         DiffSportingEvents(change.Before, change.After),
+	}
+
+	if change.Err != nil {
+		entry.Error = change.Err.Error()
+	}
+
+	return entry
+}
+
+// Record Append the changes to the audit log.
+func (auditLog *AuditLog) Record(changes []SyncChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	fileHandle, err := os.OpenFile(auditLog.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to open audit log %s: %w", auditLog.path, err)
+	}
+	defer fileHandle.Close()
+
+	encoder := json.NewEncoder(fileHandle)
+	timestamp := time.Now()
+
+	for _, change := range changes {
+		if err = encoder.Encode(NewAuditEntry(auditLog.runID, change, timestamp)); err != nil {
+			return fmt.Errorf("unable to write audit log %s: %w", auditLog.path, err)
+		}
+	}
+
+	return nil
+}
+
+// ReadAuditLog Get the audit entries that match the filter, oldest first.  There's no log until the
+// first sync changes something, so a missing log has no entries.
+func ReadAuditLog(path string, filter AuditFilter) ([]AuditEntry, error) {
+	fileHandle, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer fileHandle.Close()
+
+	var entries []AuditEntry
+
+	scanner := bufio.NewScanner(fileHandle)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		var entry AuditEntry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return entries, fmt.Errorf("%s line %d: %w", path, lineNumber, err)
+		}
+
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, scanner.Err()
+}
+
+func (filter AuditFilter) matches(entry AuditEntry) bool {
+	if filter.Event != "" && !strings.Contains(strings.ToLower(entry.EventKey), strings.ToLower(filter.Event)) {
+		return false
+	}
+
+	if filter.Worker == "" {
+		return true
+	}
+
+	for _, change := range entry.Changes {
+		if !strings.HasPrefix(change.Field, "role ") {
+			continue
+		}
+
+		if listContainsWorker(change.Before, filter.Worker) || listContainsWorker(change.After, filter.Worker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listContainsWorker Check a comma separated list of workers for a particular worker.
+func listContainsWorker(workers string, worker string) bool {
+	for _, candidate := range strings.Split(workers, ", ") {
+		if strings.EqualFold(candidate, worker) {
+			return true
+		}
+	}
+
+	return false
+}
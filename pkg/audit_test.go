@@ -0,0 +1,72 @@
+package pkg
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Before the first sync changes anything, there's no audit log, and so no history.
+func TestReadAuditLogMissing(t *testing.T) {
+	entries, err := ReadAuditLog(filepath.Join(t.TempDir(), "audit.jsonl"), AuditFilter{})
+	if err != nil {
+		t.Fatalf("ReadAuditLog: %v", err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("got %d entries, want none", len(entries))
+	}
+}
+
+func TestReadAuditLogFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	hockey := SportingEvent{
+		Datetime: time.Date(2024, 2, 3, 19, 0, 0, 0, time.UTC),
+		Sport:    "Hockey",
+		Roles:    []string{"Announcer: Pat Worker"},
+	}
+	soccer := SportingEvent{
+		Datetime: time.Date(2024, 2, 4, 13, 0, 0, 0, time.UTC),
+		Sport:    "Soccer",
+		Roles:    []string{"Announcer: Sam Worker"},
+	}
+
+	err := NewAuditLog(path, "run-1").Record([]SyncChange{
+		{Action: SyncCreate, Key: hockey.GetKey(), After: hockey},
+		{Action: SyncCreate, Key: soccer.GetKey(), After: soccer},
+	})
+	if err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filter   AuditFilter
+		wantKeys []string
+	}{
+		{"everything", AuditFilter{}, []string{hockey.GetKey(), soccer.GetKey()}},
+		{"by event", AuditFilter{Event: "hockey"}, []string{hockey.GetKey()}},
+		{"by worker", AuditFilter{Worker: "Sam Worker"}, []string{soccer.GetKey()}},
+		{"no match", AuditFilter{Worker: "Lee Worker"}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := ReadAuditLog(path, test.filter)
+			if err != nil {
+				t.Fatalf("ReadAuditLog: %v", err)
+			}
+
+			if len(entries) != len(test.wantKeys) {
+				t.Fatalf("got %d entries, want %d", len(entries), len(test.wantKeys))
+			}
+
+			for index, entry := range entries {
+				if entry.EventKey != test.wantKeys[index] || entry.RunID != "run-1" {
+					t.Errorf("entry %d = %s (run %s), want %s (run run-1)",
+						index, entry.EventKey, entry.RunID, test.wantKeys[index])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Managing the saved OAuth token: logging in, checking it still works, and logging out.
+
+const revokeURL = "https://oauth2.googleapis.com/revoke"
+
+// TokenStatus What we know about the saved token.
+type TokenStatus struct {
+	Method          string    `json:"method"`
+	Path            string    `json:"path,omitempty"` // The token file, or the service account key
+	Exists          bool      `json:"exists"`
+	HasRefreshToken bool      `json:"hasRefreshToken"`
+	Encrypted       bool      `json:"encrypted,omitempty"` // Saved tokens will be encrypted
+	Valid           bool      `json:"valid"`               // Google accepted it (after refreshing, if it had expired)
+	Revoked         bool      `json:"revoked,omitempty"`   // The refresh token was revoked or expired
+	Expiry          time.Time `json:"expiry,omitempty"`
+	Scopes          []string  `json:"scopes,omitempty"`        // What Google granted
+	MissingScopes   []string  `json:"missingScopes,omitempty"` // Needed, but not granted
+	Error           string    `json:"error,omitempty"`
+}
+
+// CheckAuth Make sure we can get a token with the configured auth method, and that it has the
+// scopes the profiles need.
+func CheckAuth(ctx context.Context, cfg *Config, profiles []string) TokenStatus {
+	config := cfg.GetAuthConfiguration()
+
+	scopes, err := ScopesFor(profiles...)
+	if err != nil {
+		return TokenStatus{Method: config.Method, Error: err.Error()}
+	}
+
+	if config.Method == AuthMethodOAuth {
+		oauthConfig, err := LoadOAuthConfig(cfg, scopes)
+		if err != nil {
+			return TokenStatus{Method: config.Method, Path: cfg.GetTokenPath(), Error: err.Error()}
+		}
+
+		return CheckToken(ctx, cfg, oauthConfig)
+	}
+
+	status := TokenStatus{Method: config.Method, Path: config.KeyPath}
+
+	tokenSource, err := newCredentialsTokenSource(ctx, config, scopes)
+	if err != nil {
+		status.Error = err.Error()
+
+		return status
+	}
+
+	status.Exists = true
+
+	token, err := tokenSource.Token()
+	if err != nil {
+		status.Error = err.Error()
+
+		return status
+	}
+
+	status.Valid = true
+	status.Expiry = token.Expiry
+
+	granted, err := FetchGrantedScopes(ctx, token)
+	if err != nil {
+		status.Error = err.Error()
+
+		return status
+	}
+
+	status.Scopes = granted
+	status.MissingScopes = MissingScopes(granted, scopes)
+
+	return status
+}
+
+// CheckToken Read the saved token and make sure it can still be used.  If Google hands out a new
+// refresh token, it's saved.
+func CheckToken(ctx context.Context, cfg *Config, config *oauth2.Config) TokenStatus {
+	status := TokenStatus{Method: AuthMethodOAuth, Path: cfg.GetTokenPath()}
+
+	store, err := NewTokenStore(status.Path, cfg.GetAuthConfiguration())
+	if err != nil {
+		status.Error = err.Error()
+
+		return status
+	}
+
+	status.Encrypted = store.passphrase != ""
+
+	token, err := store.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		return status
+	}
+
+	status.Exists = true
+
+	if err != nil {
+		status.Error = fmt.Sprintf("unable to read token: %v", err)
+
+		return status
+	}
+
+	status.HasRefreshToken = token.RefreshToken != ""
+
+	granted, err := getGrantedScopes(ctx, config, store, token, cfg.GetLogger())
+	if err != nil {
+		status.Revoked = errors.Is(err, ErrTokenRevoked)
+		status.Error = err.Error()
+
+		return status
+	}
+
+	status.Scopes = granted
+	status.MissingScopes = MissingScopes(granted, config.Scopes)
+
+	// Get an access token the way a command would, with the needed scopes that were granted.
+	check := *config
+	check.Scopes = nil
+
+	for _, scope := range config.Scopes {
+		if !slices.Contains(status.MissingScopes, scope) {
+			check.Scopes = append(check.Scopes, scope)
+		}
+	}
+
+	current, err := NewPersistingTokenSource(ctx, &check, store, token, cfg.GetLogger()).Token()
+	if err != nil {
+		status.Revoked = errors.Is(err, ErrTokenRevoked)
+		status.Error = err.Error()
+
+		return status
+	}
+
+	status.Valid = true
+	status.Expiry = current.Expiry
+
+	return status
+}
+
+// Login Ask the user to authorize us with the given flow, and save the new token.
+func Login(ctx context.Context, cfg *Config, config *oauth2.Config, flow string) error {
+	auth := cfg.GetAuthConfiguration()
+	auth.Flow = flow
+
+	token, err := GetTokenFromWeb(ctx, config, auth)
+	if err != nil {
+		return err
+	}
+
+	return SaveToken(cfg.GetTokenPath(), auth, token, cfg.GetLogger())
+}
+
+// Logout Delete the saved token, first asking Google to revoke it if revoke is set.
+func Logout(ctx context.Context, cfg *Config, revoke bool) error {
+	path := cfg.GetTokenPath()
+
+	if revoke {
+		token, err := TokenFromFile(path, cfg.GetAuthConfiguration())
+		if err != nil {
+			return fmt.Errorf("unable to read token to revoke it: %w", err)
+		}
+
+		if err = RevokeToken(ctx, token); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to delete token: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeToken Revoking the refresh token also revokes every access token issued from it.
+func RevokeToken(ctx context.Context, token *oauth2.Token) error {
+	value := token.RefreshToken
+	if value == "" {
+		value = token.AccessToken
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, revokeURL,
+		strings.NewReader(url.Values{"token": {value}}.Encode()))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("unable to revoke token: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to revoke token: %s", response.Status)
+	}
+
+	return nil
+}
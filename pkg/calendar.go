@@ -1,90 +1,20 @@
 package pkg
 
 import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/api/calendar/v3"
-	"log"
 	"strings"
 	"time"
 )
 
-func GetSportLocation(sport string) string {
-	// Build a map of the Sports to a Location string for the events
-	sportToLocation := map[string]string{
-		"Men's Tennis":                 "",
-		"Women's Tennis":               "",
-		"Men's Water Polo":             "",
-		"Women's Water Polo":           "",
-		"Men's Basketball":             "Pizzitola Sports Center, Providence, RI 02906",
-		"Women's Basketball":           "Pizzitola Sports Center, Providence, RI 02906",
-		"Wrestling":                    "",
-		"Gymnastics":                   "",
-		"Gymnastics (Rumble & Tumble)": "",
-		"Wrestling (Rumble & Tumble)":  "",
-		"Men's Ice Hockey":             "Meehan Auditorium, 225 Hope St, Providence, RI 02912",
-		"Women's Ice Hockey":           "Meehan Auditorium, 225 Hope St, Providence, RI 02912",
-		"Track and Field (OMAC)":       "Olney-Margolies Athletic Center (OMAC)",
-		"Men's Lacrosse":               "",
-		"Women's Lacrosse":             "",
-		"Baseball":                     "Terrence Murray Baseball Stadium",
-		"Softball":                     "",
-		"Men's Crew":                   "",
-	}
-	location := sportToLocation[sport]
-
-	return location
-}
-
-// GetCalendarMaps Get a map with key: datetime+sport and value: SportingEvent struct of all the future events
-// on the calendar.
-func GetCalendarMaps(
-	calendarService *calendar.Service,
-	currentTime time.Time) (map[string]SportingEvent, map[string]string) {
-	// The Google APIs deal with times in specific string formats.
-	currentTimeString := time.Now().Format(time.RFC3339)
-
-	// Retrieve ALL of the future calendar events
-	var err error
-	events, err := calendarService.Events.List(GetCalendarID()).ShowDeleted(false).
-		SingleEvents(true).TimeMin(currentTimeString).OrderBy("startTime").Do()
-
-	if err != nil {
-		log.Fatalf("Unable to retrieve the events from the calendar: %v", err)
-	}
-
-	// Create two maps.
-	// The first is for the SportingEvent struct
-	// The second is for the Calendar Event ID.  This allows us to delete or update the event.
-	// These could be merged into a single map with a tuple of values, but this seems more
-	// straightforward at the small expense of extra memory.
-	calendarFutureEvents := make(map[string]SportingEvent)
-	calendarFutureEventIds := make(map[string]string)
-
-	for _, item := range events.Items {
-		if !strings.Contains(item.Description, AutomationMarker) {
-			continue // We didn't create this entry... skip it.
-		}
-
-		sportingEvent := getSportingEventFromCalendarEvent(item)
-
-		// Although we specify TimeMin when getting the list of calendar events,
-		// this includes events with an _end time_ that if after the TimeMin.
-		// Need to check against the start time of the event to ensure calendar
-		// events aren't deleted when the event is in progress during a run.
-		if sportingEvent.Datetime.After(currentTime) {
-			calendarFutureEvents[sportingEvent.GetKey()] = sportingEvent
-			calendarFutureEventIds[sportingEvent.GetKey()] = item.Id
-		}
-	}
-
-	return calendarFutureEvents, calendarFutureEventIds
-}
-
 func getSportingEventFromCalendarEvent(calendarEvent *calendar.Event) SportingEvent {
 	var sportingEvent SportingEvent
 
 	const minRoleSize = 2
 
 	sportingEvent.Sport = calendarEvent.Summary
+	sportingEvent.Location = calendarEvent.Location
 	description := strings.ReplaceAll(calendarEvent.Description, AutomationMarker, "")
 
 	for _, role := range strings.Split(description, "\n") {
@@ -94,50 +24,60 @@ func getSportingEventFromCalendarEvent(calendarEvent *calendar.Event) SportingEv
 		}
 	}
 
-	eastern, _ := time.LoadLocation("America/New_York")
-	datetime, _ := time.ParseInLocation("2006-01-02T15:04:05-05:00", calendarEvent.Start.DateTime, eastern)
-
-	// Parser seems to set a seconds value from the timezone.  Truncate to the minute to match the spreadsheet.
-	datetime = datetime.Truncate(time.Minute)
-	sportingEvent.Datetime = datetime
+	// The calendar may give the time with another offset, so it's put back in the event's own time zone to
+	// match the spreadsheet.
+	datetime, _ := time.Parse(time.RFC3339, calendarEvent.Start.DateTime)
+	sportingEvent.Datetime = datetime.In(calendarTimeZone(calendarEvent.Start.TimeZone)).Truncate(time.Minute)
 
 	return sportingEvent
 }
 
-func CreateCalendarEvent(calendarService *calendar.Service, calendarID string, sportingEvent SportingEvent) error {
-	event := createCalendarEntryObject(sportingEvent)
-
-	var err error
+// CreateCalendarEvent Add the sporting event to the calendar and return the new calendar event.
+func CreateCalendarEvent(
+	ctx context.Context,
+	calendarService *calendar.Service,
+	calendarID string,
+	sportingEvent SportingEvent) (_ *calendar.Event, err error) {
+	ctx, span := StartSpan(ctx, "CreateCalendarEvent", attribute.String("event_key", sportingEvent.GetKey()))
+	defer func() { EndSpan(span, err) }()
 
-	_, err = calendarService.Events.Insert(calendarID, event).Do()
-	if err != nil {
-		log.Fatalf("Unable to create event. %v\n", err)
-	}
+	event := createCalendarEntryObject(sportingEvent)
 
-	return err
+	return calendarService.Events.Insert(calendarID, event).Context(ctx).Do()
 }
 
 func UpdateCalendarEvent(
+	ctx context.Context,
 	calendarService *calendar.Service,
 	calendarID string,
 	eventID string,
-	sportingEvent SportingEvent) error {
-	event := createCalendarEntryObject(sportingEvent)
+	sportingEvent SportingEvent) (_ *calendar.Event, err error) {
+	ctx, span := StartSpan(ctx, "UpdateCalendarEvent",
+		attribute.String("event_key", sportingEvent.GetKey()), attribute.String("calendar_event_id", eventID))
+	defer func() { EndSpan(span, err) }()
 
-	var err error
+	event := createCalendarEntryObject(sportingEvent)
 
-	_, err = calendarService.Events.Update(calendarID, eventID, event).Do()
+	return calendarService.Events.Update(calendarID, eventID, event).Context(ctx).Do()
+}
 
-	if err != nil {
-		log.Fatalf("Unable to update event. %v\n", err)
-	}
+func DeleteCalendarEvent(
+	ctx context.Context,
+	calendarService *calendar.Service,
+	calendarID string,
+	eventID string,
+	eventKey string) (err error) {
+	ctx, span := StartSpan(ctx, "DeleteCalendarEvent",
+		attribute.String("event_key", eventKey), attribute.String("calendar_event_id", eventID))
+	defer func() { EndSpan(span, err) }()
 
-	return err
+	return calendarService.Events.Delete(calendarID, eventID).Context(ctx).Do()
 }
 
-func createCalendarEntryObject(sportingEvent SportingEvent) *calendar.Event {
-	const durationInHours = 2
+// How long a calendar event lasts.  The spreadsheet only has start times.
+const durationInHours = 2
 
+func createCalendarEntryObject(sportingEvent SportingEvent) *calendar.Event {
 	description := ""
 	for _, role := range sportingEvent.Roles {
 		description += role + "\n"
@@ -145,19 +85,21 @@ func createCalendarEntryObject(sportingEvent SportingEvent) *calendar.Event {
 
 	description += AutomationMarker
 
+	// The event keeps its own time zone, so an away game shows its local time.
 	startTime := sportingEvent.Datetime
 	endTime := startTime.Add(time.Hour * time.Duration(durationInHours))
+	timeZone := startTime.Location().String()
 	event := &calendar.Event{
 		Summary:     sportingEvent.Sport,
-		Location:    GetSportLocation(sportingEvent.Sport),
+		Location:    sportingEvent.Location,
 		Description: description,
 		Start: &calendar.EventDateTime{
 			DateTime: startTime.Format(time.RFC3339),
-			TimeZone: "America/New_York",
+			TimeZone: timeZone,
 		},
 		End: &calendar.EventDateTime{
 			DateTime: endTime.Format(time.RFC3339),
-			TimeZone: "America/New_York",
+			TimeZone: timeZone,
 		},
 		// TODO add code to fill in the emails of people who opt-in to invites.
 		// Attendees: []*calendar.EventAttendee{
@@ -0,0 +1,316 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The calendar cache is a local copy of the events we manage on the calendar.  After the first full
+// listing, each run asks the Calendar API only for what changed since the last run (using the
+// nextSyncToken), instead of listing every future event again.
+// See https://developers.google.com/calendar/api/guides/sync for details.
+//
+// Because we remember the etag of every event we write, a change we didn't make shows up as an
+// etag we don't recognize.  That's how we spot people editing managed events by hand.
+//
+// Only events that haven't started yet matter to a sync, so past events are dropped whenever the
+// cache is saved.  Otherwise the cache would keep every event of every season.
+
+type CalendarCache struct {
+	CalendarID   string                  `json:"calendarId"`
+	SyncToken    string                  `json:"syncToken"`
+	Events       map[string]*CachedEvent `json:"events"`                 // Keyed by calendar event ID
+	PrunedBefore time.Time               `json:"prunedBefore,omitempty"` // Events starting earlier aren't kept
+
+	path     string
+	logger   *slog.Logger
+	relisted bool // The last refresh was a full listing
+}
+
+type CachedEvent struct {
+	Etag          string `json:"etag"`
+	Summary       string `json:"summary"`
+	Location      string `json:"location,omitempty"`
+	Description   string `json:"description"`
+	StartDateTime string `json:"startDateTime"`
+	StartTimeZone string `json:"startTimeZone,omitempty"`
+}
+
+// LoadCalendarCache Read the cache from a previous run.  A missing or unreadable cache, or one for a
+// different calendar, just means starting over with a full listing.
+func LoadCalendarCache(path string, calendarID string, logger *slog.Logger) *CalendarCache {
+	cache := &CalendarCache{CalendarID: calendarID, Events: make(map[string]*CachedEvent), path: path, logger: logger}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("Unable to read the calendar cache, starting over", "file", path, "error", err)
+		}
+
+		return cache
+	}
+
+	var saved CalendarCache
+	if err = json.Unmarshal(content, &saved); err != nil {
+		logger.Warn("Unable to decode the calendar cache, starting over", "file", path, "error", err)
+
+		return cache
+	}
+
+	if saved.CalendarID != calendarID || saved.Events == nil {
+		return cache
+	}
+
+	saved.path = path
+	saved.logger = logger
+
+	return &saved
+}
+
+// Save Drop the events that started before currentTime, then write the cache atomically, so a crash
+// can't leave a half-written file behind.
+func (cache *CalendarCache) Save(currentTime time.Time) error {
+	for eventID, cached := range cache.Events {
+		if cached.sportingEvent().Datetime.Before(currentTime) {
+			delete(cache.Events, eventID)
+		}
+	}
+
+	cache.PrunedBefore = currentTime
+
+	content, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = writeFileAtomically(cache.path, content); err != nil {
+		return fmt.Errorf("unable to save calendar cache: %w", err)
+	}
+
+	return nil
+}
+
+// writeFileAtomically Write to a temporary file and rename it into place, so readers see either the
+// old content or the new content and never a partial write.
+func writeFileAtomically(path string, content []byte) error {
+	temporary, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+
+	if _, err = temporary.Write(content); err != nil {
+		temporary.Close()
+
+		return err
+	}
+
+	if err = temporary.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temporary.Name(), path)
+}
+
+// Refresh Bring the cache up to date with the calendar, and return the changes to managed events that
+// we didn't make ourselves.  If the sync token has expired we start over with a full listing, which
+// can't tell us about outside changes.
+func (cache *CalendarCache) Refresh(
+	ctx context.Context,
+	calendarService *calendar.Service) (_ []SyncChange, err error) {
+	ctx, span := StartSpan(ctx, "RefreshCalendarCache", attribute.Bool("incremental", cache.SyncToken != ""))
+	defer func() { EndSpan(span, err) }()
+
+	if cache.SyncToken != "" {
+		changes, err := cache.refreshIncremental(ctx, calendarService)
+
+		var apiError *googleapi.Error
+		if !errors.As(err, &apiError) || apiError.Code != http.StatusGone {
+			return changes, err
+		}
+
+		cache.logger.Info("Calendar sync token expired, doing a full listing")
+		span.SetAttributes(attribute.Bool("incremental", false))
+	}
+
+	return nil, cache.refreshFull(ctx, calendarService)
+}
+
+func (cache *CalendarCache) refreshFull(ctx context.Context, calendarService *calendar.Service) error {
+	events := make(map[string]*CachedEvent)
+	syncToken := ""
+
+	err := calendarService.Events.List(cache.CalendarID).SingleEvents(true).
+		Pages(ctx, func(page *calendar.Events) error {
+			for _, item := range page.Items {
+				if item.Status != "cancelled" && isManaged(item) {
+					events[item.Id] = newCachedEvent(item)
+				}
+			}
+
+			if page.NextSyncToken != "" {
+				syncToken = page.NextSyncToken
+			}
+
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("unable to retrieve the events from the calendar: %w", err)
+	}
+
+	cache.Events = events
+	cache.SyncToken = syncToken
+	cache.relisted = true
+
+	return nil
+}
+
+// Relisted Whether the last refresh had to list every event, so it couldn't tell what changed.
+func (cache *CalendarCache) Relisted() bool {
+	return cache.relisted
+}
+
+func (cache *CalendarCache) refreshIncremental(
+	ctx context.Context,
+	calendarService *calendar.Service) ([]SyncChange, error) {
+	var changes []SyncChange
+
+	syncToken := ""
+	updated := make(map[string]*calendar.Event)
+
+	// Deleted events are always included in an incremental listing, with a status of "cancelled".
+	err := calendarService.Events.List(cache.CalendarID).SingleEvents(true).SyncToken(cache.SyncToken).
+		Pages(ctx, func(page *calendar.Events) error {
+			for _, item := range page.Items {
+				updated[item.Id] = item
+			}
+
+			if page.NextSyncToken != "" {
+				syncToken = page.NextSyncToken
+			}
+
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve the changed events from the calendar: %w", err)
+	}
+
+	for eventID, item := range updated {
+		cached, wasManaged := cache.Events[eventID]
+		stillManaged := item.Status != "cancelled" && isManaged(item)
+
+		switch {
+		case wasManaged && !stillManaged:
+			// Deleted, or someone removed the automation marker.  Either way, it's no longer ours.
+			delete(cache.Events, eventID)
+			changes = append(changes, cache.externalChange(eventID, cached, nil))
+		case wasManaged && item.Etag != cached.Etag:
+			cache.Events[eventID] = newCachedEvent(item)
+			changes = append(changes, cache.externalChange(eventID, cached, cache.Events[eventID]))
+		case !wasManaged && stillManaged:
+			cached = newCachedEvent(item)
+			if cached.sportingEvent().Datetime.Before(cache.PrunedBefore) {
+				// We stopped keeping track of it once it started.
+				continue
+			}
+
+			// Most likely an event we created whose insert response we never saw, or a copy of one of ours.
+			cache.Events[eventID] = cached
+			changes = append(changes, cache.externalChange(eventID, nil, cache.Events[eventID]))
+		}
+	}
+
+	cache.SyncToken = syncToken
+
+	return changes, nil
+}
+
+func (cache *CalendarCache) externalChange(eventID string, before *CachedEvent, after *CachedEvent) SyncChange {
+	change := SyncChange{Action: SyncExternal, CalendarEventID: eventID}
+
+	if before != nil {
+		change.Before = before.sportingEvent()
+		change.Key = change.Before.GetKey()
+	}
+
+	if after != nil {
+		change.After = after.sportingEvent()
+		change.Key = change.After.GetKey()
+	}
+
+	return change
+}
+
+// Remember Record an event we just wrote, so we recognize it in the next incremental listing.
+func (cache *CalendarCache) Remember(event *calendar.Event) {
+	cache.Events[event.Id] = newCachedEvent(event)
+}
+
+// Forget Drop an event we just deleted.
+func (cache *CalendarCache) Forget(eventID string) {
+	delete(cache.Events, eventID)
+}
+
+// GetFutureEventMaps Get a map with key: datetime+sport and value: SportingEvent struct of all the
+// future managed events, and a map from the same key to the calendar event ID.
+func (cache *CalendarCache) GetFutureEventMaps(currentTime time.Time) (map[string]SportingEvent, map[string]string) {
+	// Create two maps.
+	// The first is for the SportingEvent struct
+	// The second is for the Calendar Event ID.  This allows us to delete or update the event.
+	// These could be merged into a single map with a tuple of values, but this seems more
+	// straightforward at the small expense of extra memory.
+	calendarFutureEvents := make(map[string]SportingEvent)
+	calendarFutureEventIds := make(map[string]string)
+
+	for eventID, cached := range cache.Events {
+		sportingEvent := cached.sportingEvent()
+
+		// Need to check against the start time of the event to ensure calendar
+		// events aren't deleted when the event is in progress during a run.
+		if sportingEvent.Datetime.After(currentTime) {
+			calendarFutureEvents[sportingEvent.GetKey()] = sportingEvent
+			calendarFutureEventIds[sportingEvent.GetKey()] = eventID
+		}
+	}
+
+	return calendarFutureEvents, calendarFutureEventIds
+}
+
+func isManaged(event *calendar.Event) bool {
+	return strings.Contains(event.Description, AutomationMarker) && event.Start != nil
+}
+
+func newCachedEvent(event *calendar.Event) *CachedEvent {
+	cached := &CachedEvent{
+		Etag:        event.Etag,
+		Summary:     event.Summary,
+		Location:    event.Location,
+		Description: event.Description,
+	}
+	if event.Start != nil {
+		cached.StartDateTime = event.Start.DateTime
+		cached.StartTimeZone = event.Start.TimeZone
+	}
+
+	return cached
+}
+
+func (cached *CachedEvent) sportingEvent() SportingEvent {
+	return getSportingEventFromCalendarEvent(&calendar.Event{
+		Summary:     cached.Summary,
+		Location:    cached.Location,
+		Description: cached.Description,
+		Start:       &calendar.EventDateTime{DateTime: cached.StartDateTime, TimeZone: cached.StartTimeZone},
+	})
+}
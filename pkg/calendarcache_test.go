@@ -0,0 +1,103 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func managedEvent(eventID string, etag string, start time.Time) *calendar.Event {
+	return &calendar.Event{
+		Id:          eventID,
+		Etag:        etag,
+		Summary:     "Hockey",
+		Description: "Announcer: Pat Worker" + AutomationMarker,
+		Start:       &calendar.EventDateTime{DateTime: start.Format(time.RFC3339), TimeZone: "America/New_York"},
+	}
+}
+
+// fakeCalendarService A calendar service whose event listings all return the events.
+func fakeCalendarService(t *testing.T, events ...*calendar.Event) *calendar.Service {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(writer).Encode(&calendar.Events{Items: events, NextSyncToken: "next-token"})
+	}))
+	t.Cleanup(server.Close)
+
+	service, err := calendar.NewService(context.Background(),
+		option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL))
+	if err != nil {
+		t.Fatalf("calendar.NewService: %v", err)
+	}
+
+	return service
+}
+
+func TestCalendarCacheSavePrunesPastEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar_cache.json")
+	now := time.Date(2024, 2, 3, 12, 0, 0, 0, time.UTC)
+
+	cache := LoadCalendarCache(path, "calendar", slog.Default())
+	cache.Remember(managedEvent("last-season", "1", now.AddDate(0, -6, 0)))
+	cache.Remember(managedEvent("this-morning", "1", now.Add(-3*time.Hour)))
+	cache.Remember(managedEvent("tonight", "1", now.Add(7*time.Hour)))
+
+	if err := cache.Save(now); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	saved := LoadCalendarCache(path, "calendar", slog.Default())
+
+	if len(saved.Events) != 1 || saved.Events["tonight"] == nil {
+		t.Errorf("saved events = %v, want just tonight", saved.Events)
+	}
+
+	if !saved.PrunedBefore.Equal(now) {
+		t.Errorf("PrunedBefore = %s, want %s", saved.PrunedBefore, now)
+	}
+}
+
+// An old event we no longer keep isn't mistaken for one someone else added.
+func TestCalendarCacheIgnoresPrunedEvents(t *testing.T) {
+	now := time.Now().Truncate(time.Minute)
+
+	cache := LoadCalendarCache(filepath.Join(t.TempDir(), "calendar_cache.json"), "calendar", slog.Default())
+	cache.SyncToken = "token"
+	cache.Remember(managedEvent("tonight", "1", now.Add(7*time.Hour)))
+
+	if err := cache.Save(now); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	service := fakeCalendarService(t,
+		managedEvent("last-season", "2", now.AddDate(0, -6, 0)),
+		managedEvent("tonight", "2", now.Add(7*time.Hour)),
+		managedEvent("next-week", "1", now.AddDate(0, 0, 7)))
+
+	changes, err := cache.Refresh(context.Background(), service)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	changed := make(map[string]bool)
+	for _, change := range changes {
+		changed[change.CalendarEventID] = true
+	}
+
+	if len(changes) != 2 || !changed["tonight"] || !changed["next-week"] {
+		t.Errorf("external changes to %v, want tonight and next-week", changed)
+	}
+
+	if cache.Events["last-season"] != nil {
+		t.Error("the pruned event is back in the cache")
+	}
+}
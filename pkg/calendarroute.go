@@ -0,0 +1,75 @@
+package pkg
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Calendar routes send some of the events to additional calendars, so a team can subscribe to just
+// the events that matter to them: every hockey game, say, or every event that needs a photographer.
+// Each calendar is synced on its own, with its own cache, by the same engine as the main calendar.
+
+// MainCalendar The name of the calendar every event goes to, in logs and the audit log.
+const MainCalendar = "main"
+
+// CalendarRoute A calendar and the events that go to it.  An event goes to the calendar if its sport
+// matches any of Sports, or a worker is assigned to any of Roles.
+type CalendarRoute struct {
+	Name       string   `yaml:"name"`
+	CalendarID string   `yaml:"calendarId"`
+	Sports     []string `yaml:"sports"`    // Matched anywhere in the sport, ignoring case, so "Hockey" is both teams
+	Roles      []string `yaml:"roles"`     // Role names, ignoring case
+	CachePath  string   `yaml:"cachePath"` // Defaults to the calendar cache path with the name added
+}
+
+// IsMain Whether this is the main calendar, which gets every event.
+func (route CalendarRoute) IsMain() bool {
+	return route.Name == MainCalendar
+}
+
+// Matches Whether the event goes to this calendar.
+func (route CalendarRoute) Matches(sportingEvent SportingEvent) bool {
+	if route.IsMain() {
+		return true
+	}
+
+	for _, sport := range route.Sports {
+		if strings.Contains(strings.ToLower(sportingEvent.Sport), strings.ToLower(sport)) {
+			return true
+		}
+	}
+
+	for _, assignment := range sportingEvent.Assignments {
+		for _, role := range route.Roles {
+			if assignment.Worker != "" && strings.EqualFold(assignment.Role, role) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// FilterEvents Get the events that go to this calendar.
+func (route CalendarRoute) FilterEvents(sportingEvents map[string]SportingEvent) map[string]SportingEvent {
+	if route.IsMain() {
+		return sportingEvents
+	}
+
+	filtered := make(map[string]SportingEvent)
+
+	for key, sportingEvent := range sportingEvents {
+		if route.Matches(sportingEvent) {
+			filtered[key] = sportingEvent
+		}
+	}
+
+	return filtered
+}
+
+// routeCachePath calendar_cache.json becomes calendar_cache.photo.json for the photo calendar.
+func routeCachePath(mainCachePath string, name string) string {
+	extension := filepath.Ext(mainCachePath)
+
+	return strings.TrimSuffix(mainCachePath, extension) + "." + name + extension
+}
@@ -1,75 +1,834 @@
 package pkg
 
 import (
+	"errors"
+	"fmt"
 	"github.com/kelseyhightower/envconfig"
 	"gopkg.in/yaml.v3"
-	"log"
+	"io"
 	"os"
-	"sync"
+	"regexp"
+	"strings"
+	"time"
 )
 
-func GetSpreadsheetID() string {
-	return getInstance().SpreadsheetID
+// GetSpreadsheetSources Get the spreadsheets to read: the main spreadsheet (if there is one) first,
+// then the configured sources, with defaults filled in.
+func (cfg *Config) GetSpreadsheetSources() []SpreadsheetSource {
+	var sources []SpreadsheetSource
+
+	if cfg.SpreadsheetID != "" {
+		sources = append(sources, SpreadsheetSource{Name: MainSpreadsheet, SpreadsheetID: cfg.SpreadsheetID})
+	}
+
+	sources = append(sources, cfg.Spreadsheets...)
+
+	for index := range sources {
+		sources[index] = sources[index].withDefaults()
+	}
+
+	return sources
+}
+
+func (cfg *Config) GetCalendarID() string {
+	return cfg.CalendarID
+}
+
+// GetCalendarRoutes Get the calendars to sync: the main calendar first, then the configured routes,
+// with defaults filled in.
+func (cfg *Config) GetCalendarRoutes() []CalendarRoute {
+	routes := []CalendarRoute{{Name: MainCalendar, CalendarID: cfg.CalendarID, CachePath: cfg.GetCalendarCachePath()}}
+
+	for _, route := range cfg.Calendars {
+		if route.CachePath == "" {
+			route.CachePath = routeCachePath(cfg.GetCalendarCachePath(), route.Name)
+		}
+
+		routes = append(routes, route)
+	}
+
+	return routes
+}
+
+// GetSports Get the sport catalogue, or the Brown sports if there isn't one.
+func (cfg *Config) GetSports() []Sport {
+	if len(cfg.Sports) == 0 {
+		return defaultSports
+	}
+
+	return cfg.Sports
+}
+
+// GetTimeZone Get the time zone events are in, unless their venue or time says otherwise.
+func (cfg *Config) GetTimeZone() *time.Location {
+	name := cfg.TimeZone
+	if name == "" {
+		name = defaultTimeZone
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		location, _ = time.LoadLocation(defaultTimeZone)
+	}
+
+	return location
+}
+
+const defaultTimeZone = "America/New_York"
+
+var defaultSports = []Sport{
+	{Name: "Men's Tennis"},
+	{Name: "Women's Tennis"},
+	{Name: "Men's Water Polo"},
+	{Name: "Women's Water Polo"},
+	{Name: "Men's Basketball", Location: "Pizzitola Sports Center, Providence, RI 02906"},
+	{Name: "Women's Basketball", Location: "Pizzitola Sports Center, Providence, RI 02906"},
+	{Name: "Wrestling"},
+	{Name: "Gymnastics"},
+	{Name: "Gymnastics (Rumble & Tumble)"},
+	{Name: "Wrestling (Rumble & Tumble)"},
+	{Name: "Men's Ice Hockey", Location: "Meehan Auditorium, 225 Hope St, Providence, RI 02912"},
+	{Name: "Women's Ice Hockey", Location: "Meehan Auditorium, 225 Hope St, Providence, RI 02912"},
+	{Name: "Track and Field (OMAC)", Location: "Olney-Margolies Athletic Center (OMAC)"},
+	{Name: "Men's Lacrosse"},
+	{Name: "Women's Lacrosse"},
+	{Name: "Baseball", Location: "Terrence Murray Baseball Stadium"},
+	{Name: "Softball"},
+	{Name: "Men's Crew"},
+}
+
+func (cfg *Config) GetArchivePath() string {
+	if cfg.ArchivePath == "" {
+		return cfg.tenantPath(defaultArchivePath)
+	}
+
+	return cfg.ArchivePath
+}
+
+const defaultArchivePath = "archive.db"
+
+func (cfg *Config) GetAuditLogPath() string {
+	if cfg.AuditLogPath == "" {
+		return cfg.tenantPath(defaultAuditLogPath)
+	}
+
+	return cfg.AuditLogPath
+}
+
+const defaultAuditLogPath = "audit.jsonl"
+
+func (cfg *Config) GetSMTPConfiguration() SMTPConfiguration {
+	return cfg.SMTP
+}
+
+func (cfg *Config) GetDigestConfiguration() DigestConfiguration {
+	return cfg.Digest
+}
+
+func (cfg *Config) GetNotifierConfigurations() []NotifierConfiguration {
+	return cfg.Notifiers
+}
+
+// GetReminderConfiguration Get the reminder settings, with defaults filled in.
+func (cfg *Config) GetReminderConfiguration() ReminderConfiguration {
+	config := cfg.Reminders
+
+	if config.Window == 0 {
+		config.Window = defaultReminderWindow
+	}
+
+	if config.Channel == "" {
+		config.Channel = ReminderEmail
+	}
+
+	if config.StorePath == "" {
+		config.StorePath = cfg.tenantPath(defaultReminderStorePath)
+	}
+
+	return config
+}
+
+const defaultReminderWindow = 24 * time.Hour
+
+const defaultReminderStorePath = "reminders.db"
+
+func (cfg *Config) GetLockPath() string {
+	if cfg.LockPath == "" {
+		return cfg.tenantPath(defaultLockPath)
+	}
+
+	return cfg.LockPath
+}
+
+const defaultLockPath = "go-brown-sports.lock"
+
+// GetDaemonConfiguration Get the daemon settings, with defaults filled in.
+func (cfg *Config) GetDaemonConfiguration() DaemonConfiguration {
+	config := cfg.Daemon
+
+	if config.Interval == 0 {
+		config.Interval = defaultDaemonInterval
+	}
+
+	if config.GameDayInterval == 0 {
+		config.GameDayInterval = defaultGameDayInterval
+	}
+
+	if config.GameDayWindow == 0 {
+		config.GameDayWindow = defaultGameDayWindow
+	}
+
+	return config
+}
+
+const (
+	defaultDaemonInterval  = time.Hour
+	defaultGameDayInterval = 15 * time.Minute
+	defaultGameDayWindow   = 12 * time.Hour
+)
+
+// GetWatchConfiguration Get the Drive push notification settings, with defaults filled in.
+func (cfg *Config) GetWatchConfiguration() WatchConfiguration {
+	config := cfg.Watch
+
+	if config.Listen == "" {
+		config.Listen = defaultWatchListen
+	}
+
+	if config.Path == "" {
+		config.Path = defaultWatchPath
+	}
+
+	if config.Debounce == 0 {
+		config.Debounce = defaultWatchDebounce
+	}
+
+	if config.ChannelTTL == 0 {
+		config.ChannelTTL = defaultWatchChannelTTL
+	}
+
+	if config.RenewBefore == 0 {
+		config.RenewBefore = defaultWatchRenewBefore
+	}
+
+	if config.PollInterval == 0 {
+		config.PollInterval = defaultWatchPollInterval
+	}
+
+	return config
+}
+
+const (
+	defaultWatchListen       = ":8080"
+	defaultWatchPath         = "/drive/notifications"
+	defaultWatchDebounce     = 30 * time.Second
+	defaultWatchChannelTTL   = 24 * time.Hour // The most Drive allows for a file
+	defaultWatchRenewBefore  = time.Hour
+	defaultWatchPollInterval = 6 * time.Hour
+)
+
+// GetDashboardConfiguration Get the status dashboard settings, with defaults filled in.
+func (cfg *Config) GetDashboardConfiguration() DashboardConfiguration {
+	config := cfg.Dashboard
+
+	if config.Listen == "" {
+		config.Listen = defaultDashboardListen
+	}
+
+	return config
+}
+
+// The same port as the Drive notification receiver, so only one port needs to be opened.
+const defaultDashboardListen = ":8080"
+
+// GetDashboardPath Where the dashboard is served.  With tenants, each tenant's is under its name.
+func (cfg *Config) GetDashboardPath() string {
+	if cfg.tenant != "" {
+		return "/" + cfg.tenant + "/"
+	}
+
+	return "/"
+}
+
+// GetMetricsConfiguration Get the Prometheus metrics settings, with defaults filled in.
+func (cfg *Config) GetMetricsConfiguration() MetricsConfiguration {
+	config := cfg.Metrics
+
+	if config.Listen == "" {
+		config.Listen = defaultMetricsListen
+	}
+
+	if config.Path == "" {
+		config.Path = defaultMetricsPath
+	}
+
+	return config
+}
+
+const (
+	defaultMetricsListen = ":8080"
+	defaultMetricsPath   = "/metrics"
+)
+
+// GetLoggingConfiguration Get the log settings, with defaults filled in.
+func (cfg *Config) GetLoggingConfiguration() LoggingConfiguration {
+	config := cfg.Logging
+
+	if config.Level == "" {
+		config.Level = defaultLogLevel
+	}
+
+	if config.Format == "" {
+		config.Format = LogFormatText
+	}
+
+	return config
+}
+
+const defaultLogLevel = "info"
+
+// GetTracingConfiguration Get the OpenTelemetry settings, with defaults filled in.
+func (cfg *Config) GetTracingConfiguration() TracingConfiguration {
+	config := cfg.Tracing
+
+	if config.ServiceName == "" {
+		config.ServiceName = defaultTracingServiceName
+	}
+
+	return config
+}
+
+const defaultTracingServiceName = "go-brown-sports"
+
+// GetWritebackConfiguration Get the spreadsheet writeback settings, with defaults filled in.
+func (cfg *Config) GetWritebackConfiguration() WritebackConfiguration {
+	config := cfg.Writeback
+
+	if config.StatusHeader == "" {
+		config.StatusHeader = defaultWritebackStatusHeader
+	}
+
+	return config
+}
+
+const defaultWritebackStatusHeader = "Calendar Status"
+
+// GetAuthConfiguration Get the Google authentication settings, with defaults filled in.
+func (cfg *Config) GetAuthConfiguration() AuthConfiguration {
+	config := cfg.Auth
+
+	if config.Method == "" {
+		config.Method = AuthMethodOAuth
+	}
+
+	if config.Flow == "" {
+		config.Flow = AuthFlowLoopback
+	}
+
+	return config
+}
+
+func (cfg *Config) GetCredentialsPath() string {
+	if cfg.CredentialsPath == "" {
+		return cfg.tenantPath(defaultCredentialsPath)
+	}
+
+	return cfg.CredentialsPath
+}
+
+const defaultCredentialsPath = "credentials.json"
+
+func (cfg *Config) GetTokenPath() string {
+	if cfg.TokenPath == "" {
+		return cfg.tenantPath(defaultTokenPath)
+	}
+
+	return cfg.TokenPath
+}
+
+const defaultTokenPath = "token.json"
+
+func (cfg *Config) GetCalendarCachePath() string {
+	if cfg.CalendarCachePath == "" {
+		return cfg.tenantPath(defaultCalendarCachePath)
+	}
+
+	return cfg.CalendarCachePath
+}
+
+const defaultCalendarCachePath = "calendar_cache.json"
+
+func (cfg *Config) GetSyncStatePath() string {
+	if cfg.SyncStatePath == "" {
+		return cfg.tenantPath(defaultSyncStatePath)
+	}
+
+	return cfg.SyncStatePath
+}
+
+const defaultSyncStatePath = "sync_state.json"
+
+// Config Everything read from the config file and the environment.  Load it with LoadConfig, and
+// pass it to whatever needs it.
+type Config struct {
+	CalendarID        string                  `envconfig:"CALENDAR_ID"         yaml:"calendarId"`
+	SpreadsheetID     string                  `envconfig:"SPREADSHEET_ID"      yaml:"spreadsheetId"`
+	Spreadsheets      []SpreadsheetSource     `ignored:"true"                  yaml:"spreadsheets"` // More spreadsheets
+	Calendars         []CalendarRoute         `ignored:"true"                  yaml:"calendars"`    // Additional calendars
+	Sports            []Sport                 `ignored:"true"                  yaml:"sports"`       // The sport catalogue
+	Venues            []Venue                 `ignored:"true"                  yaml:"venues"`       // Away venues
+	TimeZone          string                  `envconfig:"TIME_ZONE"           yaml:"timeZone"`
+	Tenants           []TenantConfig          `ignored:"true"                  yaml:"tenants"`
+	ArchivePath       string                  `envconfig:"ARCHIVE_PATH"        yaml:"archivePath"`
+	AuditLogPath      string                  `envconfig:"AUDIT_LOG_PATH"      yaml:"auditLogPath"`
+	CalendarCachePath string                  `envconfig:"CALENDAR_CACHE_PATH" yaml:"calendarCachePath"`
+	SyncStatePath     string                  `envconfig:"SYNC_STATE_PATH"     yaml:"syncStatePath"`
+	CredentialsPath   string                  `envconfig:"CREDENTIALS_PATH"    yaml:"credentialsPath"`
+	TokenPath         string                  `envconfig:"TOKEN_PATH"          yaml:"tokenPath"`
+	SMTP              SMTPConfiguration       `envconfig:"SMTP"                yaml:"smtp"`
+	Digest            DigestConfiguration     `envconfig:"DIGEST"              yaml:"digest"`
+	Notifiers         []NotifierConfiguration `ignored:"true"                  yaml:"notifiers"`
+	Reminders         ReminderConfiguration   `envconfig:"REMINDERS"           yaml:"reminders"`
+	LockPath          string                  `envconfig:"LOCK_PATH"           yaml:"lockPath"`
+	Daemon            DaemonConfiguration     `envconfig:"DAEMON"              yaml:"daemon"`
+	Watch             WatchConfiguration      `envconfig:"WATCH"               yaml:"watch"`
+	Dashboard         DashboardConfiguration  `envconfig:"DASHBOARD"           yaml:"dashboard"`
+	Metrics           MetricsConfiguration    `envconfig:"METRICS"             yaml:"metrics"`
+	Logging           LoggingConfiguration    `envconfig:"LOG"                 yaml:"logging"`
+	Tracing           TracingConfiguration    `envconfig:"TRACING"             yaml:"tracing"`
+	Writeback         WritebackConfiguration  `envconfig:"WRITEBACK"           yaml:"writeback"`
+	Auth              AuthConfiguration       `envconfig:"AUTH"                yaml:"auth"`
+
+	tenant  string    // The tenant's name, if this is a tenant
+	tenants []*Config // Read from Tenants by LoadConfig
+}
+
+// SMTPConfiguration The mail server used for anything we email to workers.
+type SMTPConfiguration struct {
+	Host     string `split_words:"true" yaml:"host"`
+	Port     int    `split_words:"true" yaml:"port"`
+	Username string `split_words:"true" yaml:"username"`
+	Password string `split_words:"true" yaml:"password"`
+	From     string `split_words:"true" yaml:"from"`
 }
 
-func GetCalendarID() string {
-	return getInstance().CalendarID
+// DigestConfiguration Settings for emailing change digests to workers.
+type DigestConfiguration struct {
+	Enabled      bool     `split_words:"true" yaml:"enabled"`
+	Organization string   `split_words:"true" yaml:"organization"` // Optional, named in the built-in subject and body
+	Subject      string   `split_words:"true" yaml:"subject"`      // Optional template, overrides the built-in subject
+	TextTemplate string   `split_words:"true" yaml:"textTemplate"` // Optional path, overrides the built-in template
+	HTMLTemplate string   `split_words:"true" yaml:"htmlTemplate"` // Optional path, overrides the built-in template
+	OptOut       []string `split_words:"true" yaml:"optOut"`       // Worker names or email addresses
 }
 
-// TODO can this be used without a global variable?
-var lock = &sync.Mutex{}
+// NotifierConfiguration A Slack or Discord incoming webhook, or a generic JSON webhook.
+type NotifierConfiguration struct {
+	Type     string `yaml:"type"` // slack, discord or webhook
+	URL      string `yaml:"url"`
+	Template string `yaml:"template"` // Optional path, overrides the built-in template
+}
+
+// ReminderConfiguration Settings for the pre-event reminders sent by the remind command.
+type ReminderConfiguration struct {
+	Window       time.Duration `split_words:"true" yaml:"window"`  // Remind about events starting within this long
+	Channel      string        `split_words:"true" yaml:"channel"` // email or webhook
+	WebhookURL   string        `split_words:"true" yaml:"webhookUrl"`
+	StorePath    string        `split_words:"true" yaml:"storePath"`    // Where sent reminders are recorded
+	TextTemplate string        `split_words:"true" yaml:"textTemplate"` // Optional path, overrides the built-in template
+	HTMLTemplate string        `split_words:"true" yaml:"htmlTemplate"` // Optional path, overrides the built-in template
+}
+
+// DaemonConfiguration Settings for how often the serve command syncs.
+type DaemonConfiguration struct {
+	Schedule        string        `split_words:"true" yaml:"schedule"` // Cron expression, overrides the intervals
+	Interval        time.Duration `split_words:"true" yaml:"interval"`
+	GameDayInterval time.Duration `split_words:"true" yaml:"gameDayInterval"` // Used when an event is coming up soon
+	GameDayWindow   time.Duration `split_words:"true" yaml:"gameDayWindow"`   // How soon is "coming up soon"
+}
+
+// WatchConfiguration Settings for syncing when Drive tells the serve command the spreadsheet changed.
+type WatchConfiguration struct {
+	Enabled      bool          `split_words:"true" yaml:"enabled"`
+	Address      string        `split_words:"true" yaml:"address"` // Public HTTPS URL that reaches Listen + Path
+	Listen       string        `split_words:"true" yaml:"listen"`
+	Path         string        `split_words:"true" yaml:"path"`
+	Token        string        `split_words:"true" yaml:"token"` // Shared secret, random if not set
+	Debounce     time.Duration `split_words:"true" yaml:"debounce"`
+	ChannelTTL   time.Duration `split_words:"true" yaml:"channelTtl"`
+	RenewBefore  time.Duration `split_words:"true" yaml:"renewBefore"`
+	PollInterval time.Duration `split_words:"true" yaml:"pollInterval"` // Safety net while notifications are working
+}
+
+// DashboardConfiguration Settings for the status dashboard and JSON API served by the serve command.
+type DashboardConfiguration struct {
+	Enabled  bool   `split_words:"true" yaml:"enabled"`
+	Listen   string `split_words:"true" yaml:"listen"`
+	Username string `split_words:"true" yaml:"username"` // Optional HTTP basic auth
+	Password string `split_words:"true" yaml:"password"`
+}
 
-type Configuration struct {
-	CalendarID    string `envconfig:"CALENDAR_ID"    yaml:"calendarId"`
-	SpreadsheetID string `envconfig:"SPREADSHEET_ID" yaml:"spreadsheetId"`
+// MetricsConfiguration Settings for the Prometheus metrics served by the serve command.
+type MetricsConfiguration struct {
+	Enabled bool   `split_words:"true" yaml:"enabled"`
+	Listen  string `split_words:"true" yaml:"listen"`
+	Path    string `split_words:"true" yaml:"path"`
 }
 
-func newConfiguration() *Configuration {
-	var cfg Configuration
+// LoggingConfiguration How much to log, and whether as text or JSON.
+type LoggingConfiguration struct {
+	Level  string `split_words:"true" yaml:"level"`  // debug, info, warn or error
+	Format string `split_words:"true" yaml:"format"` // text or json
+}
 
-	readConfig(&cfg)
-	readEnv(&cfg)
+// TracingConfiguration Where to send OpenTelemetry spans.
+type TracingConfiguration struct {
+	Exporter    string `split_words:"true" yaml:"exporter"` // otlp or stdout.  Tracing is off if not set.
+	Endpoint    string `split_words:"true" yaml:"endpoint"` // OTLP/HTTP collector host:port
+	Insecure    bool   `split_words:"true" yaml:"insecure"` // Use HTTP rather than HTTPS for OTLP
+	ServiceName string `split_words:"true" yaml:"serviceName"`
+}
 
-	return &cfg
+// WritebackConfiguration Settings for writing each row's sync status and problems back into the spreadsheet.
+type WritebackConfiguration struct {
+	Enabled      bool   `split_words:"true" yaml:"enabled"` // Needs write access to the spreadsheet
+	StatusHeader string `split_words:"true" yaml:"statusHeader"`
 }
 
-func readConfig(cfg *Configuration) {
-	filename := "config.yaml"
-	fileHandle, err := os.Open(filename)
+// AuthConfiguration How to authenticate to the Google APIs.
+type AuthConfiguration struct {
+	Method  string `split_words:"true" yaml:"method"`  // oauth, serviceAccount or default
+	KeyPath string `split_words:"true" yaml:"keyPath"` // The service account's JSON key
+	Subject string `split_words:"true" yaml:"subject"` // User to impersonate, with domain-wide delegation
+
+	Flow         string `split_words:"true" yaml:"flow"`         // How a user authorizes oauth: loopback
+	LoopbackPort int    `split_words:"true" yaml:"loopbackPort"` // 0 picks any free port
+
+	TokenKey     string `split_words:"true" yaml:"-"`            // Passphrase for encrypting the token file
+	TokenKeyFile string `split_words:"true" yaml:"tokenKeyFile"` // Or a file with the passphrase in it
+}
+
+// LoadConfig Read the config file, then let environment variables override it.  If the file doesn't
+// exist and mustExist is false, the config comes from the environment alone.  Tenants start with the
+// result, then their own settings from the file.
+func LoadConfig(path string, mustExist bool) (*Config, error) {
+	var cfg Config
+
+	if err := readConfig(&cfg, path, mustExist); err != nil {
+		return nil, err
+	}
+
+	if err := envconfig.Process("", &cfg); err != nil {
+		return nil, fmt.Errorf("unable to read environment variables: %w", err)
+	}
+
+	if err := cfg.loadTenants(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+func readConfig(cfg *Config, path string, mustExist bool) error {
+	fileHandle, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) && !mustExist {
+		return nil
+	}
 
 	if err != nil {
-		log.Printf("Could not load %s file: %v", filename, err)
-		// Return so we don't report on a decoding error for a file we couldn't load.
-		return
+		return fmt.Errorf("unable to open config file: %w", err)
 	}
 	defer fileHandle.Close()
 
 	decoder := yaml.NewDecoder(fileHandle)
-	err = decoder.Decode(cfg)
 
-	if err != nil {
-		log.Printf("Error decoding %s: %v", filename, err)
+	// An empty file is fine, it just doesn't set anything.
+	if err = decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("unable to decode config file %s: %w", path, err)
 	}
+
+	return nil
 }
 
-func readEnv(cfg *Configuration) {
-	err := envconfig.Process("", cfg)
-	if err != nil {
-		log.Printf("Error reading environment variables: %v", err)
+// Calendar IDs are "primary" or look like email addresses, e.g. abc123@group.calendar.google.com.
+var calendarIDPattern = regexp.MustCompile(`^(primary|[^\s@/]+@[^\s@/]+\.[^\s@/]+)$`)
+
+// Spreadsheet IDs are the long base64url string in the spreadsheet's URL.
+var spreadsheetIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{20,}$`)
+
+// Route names go into file names, so keep them simple.
+var routeNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+var spreadsheetURLPattern = regexp.MustCompile(`/spreadsheets/d/([A-Za-z0-9_-]+)`)
+
+// Validate Report every mistake in the config at once, so they can all be fixed before the first run
+// rather than one at a time as each is hit.  Commands that don't read the spreadsheet or calendar pass
+// requireIDs false, so they work without them.  With tenants, each tenant is checked.
+func (cfg *Config) Validate(requireIDs bool) error {
+	var problems []error
+
+	if len(cfg.Tenants) > 0 {
+		problems = cfg.validateTenants(requireIDs)
+	} else {
+		problems = cfg.problems(requireIDs)
+	}
+
+	return errors.Join(append(problems, cfg.validateHTTPHandlers()...)...)
+}
+
+// validateHTTPHandlers The metrics, the dashboards and the Drive notification receivers can share a
+// listen address, but each needs a path of its own there.  Checked the way serve sets them up, for
+// the whole process.
+func (cfg *Config) validateHTTPHandlers() []error {
+	var problems []error
+
+	users := make(map[string]string) // Keyed by listen address and path
+
+	use := func(listen string, path string, user string) {
+		if other, found := users[listen+" "+path]; found {
+			problems = append(problems, fmt.Errorf("%s and %s are both served at %s on %s", other, user, path, listen))
+
+			return
+		}
+
+		users[listen+" "+path] = user
+	}
+
+	if metrics := cfg.GetMetricsConfiguration(); metrics.Enabled {
+		use(metrics.Listen, metrics.Path, "metrics.path")
+	}
+
+	dashboard := cfg.GetDashboardConfiguration()
+
+	for _, tenantCfg := range cfg.GetTenants() {
+		where := ""
+		if name := tenantCfg.GetTenantName(); name != "" {
+			where = fmt.Sprintf("tenant %s's ", name)
+		}
+
+		if dashboard.Enabled {
+			use(dashboard.Listen, tenantCfg.GetDashboardPath(), where+"dashboard")
+		}
+
+		// Each tenant needs its own notifications, to know which spreadsheets changed.
+		if watch := tenantCfg.GetWatchConfiguration(); watch.Enabled {
+			use(watch.Listen, watch.Path, where+"watch.path")
+		}
+	}
+
+	return problems
+}
+
+func (cfg *Config) problems(requireIDs bool) []error {
+	var problems []error
+
+	addProblem := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	switch {
+	case cfg.CalendarID == "" && requireIDs:
+		addProblem("calendarId (CALENDAR_ID) is not set")
+	case cfg.CalendarID != "" && !calendarIDPattern.MatchString(cfg.CalendarID):
+		addProblem("calendarId %q is not a calendar ID, expected primary or an ID like "+
+			"abc123@group.calendar.google.com from the calendar's settings", cfg.CalendarID)
+	}
+
+	if cfg.SpreadsheetID == "" && len(cfg.Spreadsheets) == 0 && requireIDs {
+		addProblem("spreadsheetId (SPREADSHEET_ID) is not set")
+	}
+
+	if cfg.SpreadsheetID != "" {
+		if err := validateSpreadsheetID("spreadsheetId", cfg.SpreadsheetID); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	problems = append(problems, cfg.validateSpreadsheetSources()...)
+	problems = append(problems, cfg.validateCalendarRoutes()...)
+
+	auth := cfg.GetAuthConfiguration()
+
+	switch auth.Method {
+	case AuthMethodOAuth, AuthMethodDefault:
+	case AuthMethodServiceAccount:
+		if auth.KeyPath == "" {
+			addProblem("auth.keyPath is required with auth method %s", AuthMethodServiceAccount)
+		}
+	default:
+		addProblem("unknown auth.method %q, expected %s, %s or %s",
+			auth.Method, AuthMethodOAuth, AuthMethodServiceAccount, AuthMethodDefault)
+	}
+
+	if auth.Subject != "" && auth.Method != AuthMethodServiceAccount {
+		addProblem("auth.subject only works with auth method %s", AuthMethodServiceAccount)
 	}
+
+	if err := CheckAuthFlow(auth.Flow); err != nil {
+		addProblem("auth.flow: %w", err)
+	}
+
+	for index, notifier := range cfg.GetNotifierConfigurations() {
+		if err := checkNotifier(notifier); err != nil {
+			addProblem("notifiers[%d]: %w", index, err)
+		}
+	}
+
+	if schedule := cfg.GetDaemonConfiguration().Schedule; schedule != "" {
+		if _, err := ParseCronSchedule(schedule); err != nil {
+			addProblem("daemon.schedule: %w", err)
+		}
+	}
+
+	watch := cfg.GetWatchConfiguration()
+	if watch.Enabled && !strings.HasPrefix(watch.Address, "https://") {
+		addProblem("watch.address must be a public https:// URL when watch is enabled")
+	}
+
+	if metricsPath := cfg.GetMetricsConfiguration().Path; !strings.HasPrefix(metricsPath, "/") {
+		addProblem("metrics.path %q must start with /, like %s", metricsPath, defaultMetricsPath)
+	}
+
+	sports := make(map[string]bool)
+
+	for index, sport := range cfg.Sports {
+		switch {
+		case sport.Name == "":
+			addProblem("sports[%d] has no name", index)
+		case sports[sport.Name]:
+			addProblem("sports: %s is listed more than once", sport.Name)
+		}
+
+		sports[sport.Name] = true
+	}
+
+	if cfg.TimeZone != "" {
+		if _, err := time.LoadLocation(cfg.TimeZone); err != nil {
+			addProblem("timeZone %q is not a time zone, expected one like America/New_York", cfg.TimeZone)
+		}
+	}
+
+	venues := make(map[string]bool)
+
+	for index, venue := range cfg.Venues {
+		switch {
+		case venue.Name == "":
+			addProblem("venues[%d] has no name", index)
+		case venues[strings.ToLower(venue.Name)]:
+			addProblem("venues: %s is listed more than once", venue.Name)
+		}
+
+		if venue.TimeZone != "" {
+			if _, err := time.LoadLocation(venue.TimeZone); err != nil {
+				addProblem("venues %s: timeZone %q is not a time zone, expected one like America/Chicago",
+					venue.Name, venue.TimeZone)
+			}
+		}
+
+		venues[strings.ToLower(venue.Name)] = true
+	}
+
+	return problems
 }
 
-var singleInstance *Configuration
+// validateCalendarRoutes Each route needs its own name and calendar, and something to match.
+func (cfg *Config) validateCalendarRoutes() []error {
+	var problems []error
+
+	names := map[string]bool{MainCalendar: true}
+	calendarIDs := map[string]bool{cfg.CalendarID: true}
 
-func getInstance() *Configuration {
-	if singleInstance == nil {
-		lock.Lock()
-		defer lock.Unlock()
+	for index, route := range cfg.Calendars {
+		where := fmt.Sprintf("calendars[%d]", index)
+		if route.Name != "" {
+			where = fmt.Sprintf("calendars %s", route.Name)
+		}
+
+		switch {
+		case route.Name == "":
+			problems = append(problems, fmt.Errorf("%s has no name", where))
+		case names[route.Name]:
+			problems = append(problems, fmt.Errorf("%s: the name %s is already used", where, route.Name))
+		case !routeNamePattern.MatchString(route.Name):
+			problems = append(problems, fmt.Errorf("%s: the name can only have letters, digits, - and _", where))
+		}
 
-		if singleInstance == nil {
-			singleInstance = newConfiguration()
+		switch {
+		case route.CalendarID == "":
+			problems = append(problems, fmt.Errorf("%s has no calendarId", where))
+		case !calendarIDPattern.MatchString(route.CalendarID):
+			problems = append(problems, fmt.Errorf("%s: calendarId %q is not a calendar ID", where, route.CalendarID))
+		case calendarIDs[route.CalendarID]:
+			problems = append(problems, fmt.Errorf("%s: calendar %s is already used", where, route.CalendarID))
 		}
+
+		if len(route.Sports) == 0 && len(route.Roles) == 0 {
+			problems = append(problems, fmt.Errorf("%s needs sports or roles to say which events go to it", where))
+		}
+
+		names[route.Name] = true
+		calendarIDs[route.CalendarID] = true
+	}
+
+	return problems
+}
+
+func validateSpreadsheetID(field string, spreadsheetID string) error {
+	switch {
+	case spreadsheetIDPattern.MatchString(spreadsheetID):
+		return nil
+	case spreadsheetURLPattern.MatchString(spreadsheetID):
+		return fmt.Errorf("%s is the spreadsheet's URL, use just the ID from it: %s",
+			field, spreadsheetURLPattern.FindStringSubmatch(spreadsheetID)[1])
+	default:
+		return fmt.Errorf("%s %q is not a spreadsheet ID, expected the long ID from the spreadsheet's URL",
+			field, spreadsheetID)
+	}
+}
+
+// validateSpreadsheetSources Each source needs its own name and spreadsheet, and a separate column
+// for each of the date, time and sport.
+func (cfg *Config) validateSpreadsheetSources() []error {
+	var problems []error
+
+	names := map[string]bool{MainSpreadsheet: cfg.SpreadsheetID != ""}
+	spreadsheetIDs := map[string]bool{cfg.SpreadsheetID: cfg.SpreadsheetID != ""}
+
+	for index, source := range cfg.Spreadsheets {
+		where := fmt.Sprintf("spreadsheets[%d]", index)
+		if source.Name != "" {
+			where = fmt.Sprintf("spreadsheets %s", source.Name)
+		}
+
+		switch {
+		case source.Name == "":
+			problems = append(problems, fmt.Errorf("%s has no name", where))
+		case names[source.Name]:
+			problems = append(problems, fmt.Errorf("%s: the name %s is already used", where, source.Name))
+		}
+
+		switch {
+		case source.SpreadsheetID == "":
+			problems = append(problems, fmt.Errorf("%s has no spreadsheetId", where))
+		case spreadsheetIDs[source.SpreadsheetID]:
+			problems = append(problems, fmt.Errorf("%s: spreadsheet %s is already used", where, source.SpreadsheetID))
+		default:
+			if err := validateSpreadsheetID(where+": spreadsheetId", source.SpreadsheetID); err != nil {
+				problems = append(problems, err)
+			}
+		}
+
+		columns := source.withDefaults().Columns
+		headers := map[string]bool{columns.Date: true, columns.Time: true, columns.Sport: true, columns.Venue: true}
+
+		if len(headers) < 4 {
+			problems = append(problems,
+				fmt.Errorf("%s: the date, time, sport and venue columns need different headers", where))
+		}
+
+		names[source.Name] = true
+		spreadsheetIDs[source.SpreadsheetID] = true
 	}
 
-	return singleInstance
+	return problems
 }
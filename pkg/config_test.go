@@ -0,0 +1,237 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Generic variables like PATH and PORT are set in most deployments, and mustn't be taken for ours.
+func TestLoadConfigIgnoresGenericEnvironment(t *testing.T) {
+	t.Setenv("PATH", "/usr/local/go/bin:/usr/bin:/bin")
+	t.Setenv("PORT", "8080")
+	t.Setenv("USERNAME", "someone")
+	t.Setenv("PASSWORD", "secret")
+	t.Setenv("HOST", "example.com")
+	t.Setenv("ENABLED", "true")
+	t.Setenv("LEVEL", "debug")
+	t.Setenv("METHOD", "default")
+	t.Setenv("LISTEN", ":9999")
+	t.Setenv("TOKEN", "not-the-channel-token")
+
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml"), false)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if cfg.SMTP != (SMTPConfiguration{}) {
+		t.Errorf("smtp = %+v, want it empty", cfg.SMTP)
+	}
+
+	if cfg.Digest.Enabled || cfg.Writeback.Enabled {
+		t.Errorf("digest.enabled = %v, writeback.enabled = %v, want false", cfg.Digest.Enabled, cfg.Writeback.Enabled)
+	}
+
+	if cfg.Logging.Level != "" {
+		t.Errorf("logging.level = %q, want it empty", cfg.Logging.Level)
+	}
+
+	if cfg.Auth.Method != "" {
+		t.Errorf("auth.method = %q, want it empty", cfg.Auth.Method)
+	}
+
+	if watch := cfg.Watch; watch.Enabled || watch.Path != "" || watch.Listen != "" || watch.Token != "" {
+		t.Errorf("watch = %+v, want it unset", watch)
+	}
+
+	if cfg.Dashboard != (DashboardConfiguration{}) {
+		t.Errorf("dashboard = %+v, want it unset", cfg.Dashboard)
+	}
+
+	if metricsPath := cfg.GetMetricsConfiguration().Path; metricsPath != defaultMetricsPath {
+		t.Errorf("metrics.path = %q, want %s", metricsPath, defaultMetricsPath)
+	}
+}
+
+func TestValidateDeviceFlow(t *testing.T) {
+	cfg := &Config{Auth: AuthConfiguration{Flow: AuthFlowDevice}}
+
+	if err := cfg.Validate(false); err == nil || !strings.Contains(err.Error(), "auth.flow") {
+		t.Errorf("Validate() = %v, want an auth.flow problem", err)
+	}
+}
+
+func TestValidateMetricsPath(t *testing.T) {
+	cfg := &Config{Metrics: MetricsConfiguration{Path: "usr/bin:/bin"}}
+
+	if err := cfg.Validate(false); err == nil || !strings.Contains(err.Error(), "metrics.path") {
+		t.Errorf("Validate() = %v, want a metrics.path problem", err)
+	}
+}
+
+func TestValidateNotifiers(t *testing.T) {
+	tests := []struct {
+		name     string
+		notifier NotifierConfiguration
+		want     string // "" if the notifier is fine
+	}{
+		{"slack", NotifierConfiguration{Type: NotifierSlack, URL: "https://hooks.slack.com/services/T/B/X"}, ""},
+		{"local webhook", NotifierConfiguration{Type: NotifierWebhook, URL: "http://localhost:9000/hook"}, ""},
+		{"unknown type", NotifierConfiguration{Type: "teams", URL: "https://example.com/hook"}, "unknown notifier type"},
+		{"no URL", NotifierConfiguration{Type: NotifierDiscord}, "has no URL"},
+		{"not a URL", NotifierConfiguration{Type: NotifierSlack, URL: "hooks.slack.com/services"}, "is not an http"},
+		{"wrong scheme", NotifierConfiguration{Type: NotifierWebhook, URL: "ftp://example.com/hook"}, "is not an http"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &Config{Notifiers: []NotifierConfiguration{test.notifier}}
+
+			err := cfg.Validate(false)
+
+			switch {
+			case test.want == "" && err != nil:
+				t.Errorf("Validate() = %v, want no problems", err)
+			case test.want != "" && (err == nil || !strings.Contains(err.Error(), "notifiers[0]: ") ||
+				!strings.Contains(err.Error(), test.want)):
+				t.Errorf("Validate() = %v, want a notifiers[0] problem containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigReadsPrefixedEnvironment(t *testing.T) {
+	t.Setenv("SMTP_PORT", "2525")
+	t.Setenv("SMTP_USERNAME", "sync")
+	t.Setenv("DIGEST_OPT_OUT", "Pat,Sam")
+	t.Setenv("REMINDERS_WEBHOOK_URL", "https://example.com/hook")
+	t.Setenv("DAEMON_GAME_DAY_INTERVAL", "5m")
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("AUTH_TOKEN_KEY", "passphrase")
+	t.Setenv("WATCH_PATH", "/drive")
+	t.Setenv("WATCH_CHANNEL_TTL", "1h")
+	t.Setenv("DASHBOARD_USERNAME", "coordinator")
+	t.Setenv("METRICS_PATH", "/prometheus")
+
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml"), false)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"smtp.port", cfg.SMTP.Port, 2525},
+		{"smtp.username", cfg.SMTP.Username, "sync"},
+		{"digest.optOut", len(cfg.Digest.OptOut), 2},
+		{"reminders.webhookUrl", cfg.Reminders.WebhookURL, "https://example.com/hook"},
+		{"daemon.gameDayInterval", cfg.Daemon.GameDayInterval.String(), "5m0s"},
+		{"logging.level", cfg.Logging.Level, "debug"},
+		{"auth.tokenKey", cfg.Auth.TokenKey, "passphrase"},
+		{"watch.path", cfg.Watch.Path, "/drive"},
+		{"watch.channelTtl", cfg.Watch.ChannelTTL.String(), "1h0m0s"},
+		{"dashboard.username", cfg.Dashboard.Username, "coordinator"},
+		{"metrics.path", cfg.Metrics.Path, "/prometheus"},
+	}
+
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s = %v, want %v", test.name, test.got, test.want)
+		}
+	}
+}
+
+// serve would panic registering two handlers for the same path on the same address.
+func TestValidateHTTPHandlers(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string // "" if there's no collision
+	}{
+		{
+			"defaults",
+			"watch: {enabled: true, address: https://example.com/drive}\n" +
+				"dashboard: {enabled: true}\nmetrics: {enabled: true}\n",
+			"",
+		},
+		{
+			"watch at the dashboard's root",
+			"watch: {enabled: true, address: https://example.com/, path: /}\ndashboard: {enabled: true}\n",
+			"dashboard and watch.path are both served at / on :8080",
+		},
+		{
+			"metrics at the watch path",
+			"watch: {enabled: true, address: https://example.com/drive, path: /drive}\n" +
+				"metrics: {enabled: true, path: /drive}\n",
+			"metrics.path and watch.path are both served at /drive on :8080",
+		},
+		{
+			"metrics at the watch path on another address",
+			"watch: {enabled: true, address: https://example.com/drive, path: /drive}\n" +
+				"metrics: {enabled: true, path: /drive, listen: ':9090'}\n",
+			"",
+		},
+		{
+			"two tenants watching one path",
+			"watch: {enabled: true, address: https://example.com/drive}\ntenants: [{name: brown}, {name: yale}]\n",
+			"tenant brown's watch.path and tenant yale's watch.path are both served at /drive/notifications on :8080",
+		},
+		{
+			"a tenant's watch path at another tenant's dashboard",
+			"dashboard: {enabled: true}\ntenants:\n  - name: brown\n" +
+				"    watch: {enabled: true, address: https://example.com/yale/, path: /yale/}\n  - name: yale\n",
+			"tenant brown's watch.path and tenant yale's dashboard are both served at /yale/ on :8080",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(test.config), 0o600); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+
+			cfg, err := LoadConfig(path, true)
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+
+			err = cfg.Validate(false)
+
+			switch {
+			case test.want == "" && err != nil:
+				t.Errorf("Validate() = %v, want no problems", err)
+			case test.want != "" && (err == nil || !strings.Contains(err.Error(), test.want)):
+				t.Errorf("Validate() = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigTenantDigestOrganization(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := "digest: {enabled: true, organization: Brown}\n" +
+		"tenants:\n  - name: brown\n  - name: yale\n    digest: {organization: Yale}\n"
+
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	cfg, err := LoadConfig(path, true)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	want := map[string]string{"brown": "Brown", "yale": "Yale"}
+
+	for _, tenant := range cfg.GetTenants() {
+		name := tenant.GetTenantName()
+
+		if got := tenant.GetDigestConfiguration().Organization; got != want[name] {
+			t.Errorf("tenant %s digest.organization = %q, want %q", name, got, want[name])
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package pkg
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// Diagnostics Problems found while parsing the spreadsheet.  None of these stop the sync, but the
+// affected rows or workers won't be on the calendar the way the coordinator expects.
+type Diagnostics struct {
+	Problems       []Diagnostic
+	UnknownWorkers map[string]int // Names that aren't in the Worker Contact Info tab, and how often they appear
+
+	findings    []Diagnostic // The problems, plus each appearance of an unknown worker
+	spreadsheet string       // The source being read
+	logger      *slog.Logger
+}
+
+// Severity How much a problem matters to the sync.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // The row, or the whole tab, is wrong on the calendar or missing from it
+	SeverityWarning Severity = "warning" // Something is left off the calendar, like a worker's invite
+	SeverityInfo    Severity = "info"    // Worth knowing, but nothing needs to change
+)
+
+// The rules checked while parsing the spreadsheet.
+const (
+	RuleEmptyTab           = "empty-tab"
+	RuleSkippedTab         = "skipped-tab"
+	RuleUnexpectedHeader   = "unexpected-header"
+	RuleMissingFields      = "missing-fields"
+	RuleUnparsableDatetime = "unparsable-datetime"
+	RuleRowLength          = "row-length"
+	RuleUnknownWorker      = "unknown-worker"
+	RuleMissingEmail       = "missing-email"
+	RuleAmbiguousFirstName = "ambiguous-first-name"
+	RuleDuplicateEvent     = "duplicate-event"
+	RuleUnknownSport       = "unknown-sport"
+	RuleUnknownVenue       = "unknown-venue"
+)
+
+type Diagnostic struct {
+	Rule        string   `json:"rule"`
+	Severity    Severity `json:"severity"`
+	Spreadsheet string   `json:"spreadsheet,omitempty"` // The name of the spreadsheet source
+	Tab         string   `json:"tab"`
+	Row         int      `json:"row"`              // The spreadsheet row number, or 0 if the problem isn't with a particular row
+	Column      int      `json:"column,omitempty"` // The spreadsheet column number (A is 1), or 0 if it's the whole row
+	Cell        string   `json:"cell,omitempty"`   // A1 reference to the cell, if there's a particular one
+	Message     string   `json:"message"`
+	Fix         string   `json:"fix,omitempty"` // What the coordinator can do about it
+}
+
+type UnknownWorker struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func NewDiagnostics(logger *slog.Logger) *Diagnostics {
+	return &Diagnostics{UnknownWorkers: make(map[string]int), logger: logger}
+}
+
+// SetSpreadsheet Problems added from now on are in the named spreadsheet source, unless they say otherwise.
+func (diagnostics *Diagnostics) SetSpreadsheet(name string) {
+	diagnostics.spreadsheet = name
+}
+
+// Add Record (and log) a problem.  The cell reference is filled in from the row and column.
+func (diagnostics *Diagnostics) Add(diagnostic Diagnostic) {
+	diagnostic.Cell = CellReference(diagnostic.Column, diagnostic.Row)
+
+	if diagnostic.Spreadsheet == "" {
+		diagnostic.Spreadsheet = diagnostics.spreadsheet
+	}
+
+	diagnostics.Problems = append(diagnostics.Problems, diagnostic)
+	diagnostics.findings = append(diagnostics.findings, diagnostic)
+
+	diagnostics.logger.Warn("Spreadsheet problem", diagnostic.logAttributes()...)
+}
+
+// AddUnknownWorker Record a name in the cell at row, column that isn't in the Worker Contact Info tab.
+func (diagnostics *Diagnostics) AddUnknownWorker(tab string, row int, column int, name string, fix string) {
+	diagnostics.UnknownWorkers[name]++
+
+	diagnostic := Diagnostic{
+		Rule:        RuleUnknownWorker,
+		Severity:    SeverityWarning,
+		Spreadsheet: diagnostics.spreadsheet,
+		Tab:         tab,
+		Row:         row,
+		Column:      column,
+		Cell:        CellReference(column, row),
+		Message:     fmt.Sprintf("%s is not in the %s tab, so they won't be invited", name, WorkerContactInfoTab),
+		Fix:         fix,
+	}
+	diagnostics.findings = append(diagnostics.findings, diagnostic)
+
+	diagnostics.logger.Debug("Worker is not in the Worker Contact Info tab", "tab", tab, "row", row, "worker", name)
+}
+
+// Findings Every problem, including each appearance of an unknown worker, in the order found.
+func (diagnostics *Diagnostics) Findings() []Diagnostic {
+	return append([]Diagnostic{}, diagnostics.findings...)
+}
+
+// GetUnknownWorkers Get the unknown workers, most frequent first.
+func (diagnostics *Diagnostics) GetUnknownWorkers() []UnknownWorker {
+	unknownWorkers := make([]UnknownWorker, 0, len(diagnostics.UnknownWorkers))
+	for name, count := range diagnostics.UnknownWorkers {
+		unknownWorkers = append(unknownWorkers, UnknownWorker{Name: name, Count: count})
+	}
+
+	sort.Slice(unknownWorkers, func(i, j int) bool {
+		if unknownWorkers[i].Count != unknownWorkers[j].Count {
+			return unknownWorkers[i].Count > unknownWorkers[j].Count
+		}
+
+		return unknownWorkers[i].Name < unknownWorkers[j].Name
+	})
+
+	return unknownWorkers
+}
+
+func (diagnostic Diagnostic) logAttributes() []any {
+	attributes := []any{"rule", diagnostic.Rule, "severity", diagnostic.Severity}
+
+	if diagnostic.Spreadsheet != "" && diagnostic.Spreadsheet != MainSpreadsheet {
+		attributes = append(attributes, "spreadsheet", diagnostic.Spreadsheet)
+	}
+
+	attributes = append(attributes, "tab", diagnostic.Tab)
+
+	switch {
+	case diagnostic.Cell != "":
+		attributes = append(attributes, "cell", diagnostic.Cell)
+	case diagnostic.Row != 0:
+		attributes = append(attributes, "row", diagnostic.Row)
+	}
+
+	return append(attributes, "problem", diagnostic.Message)
+}
+
+// CellReference The A1 reference for a cell, like "C12".  Columns and rows are numbered from 1.  If
+// either is 0, there's no particular cell.
+func CellReference(column int, row int) string {
+	if column <= 0 || row <= 0 {
+		return ""
+	}
+
+	return ColumnName(column) + fmt.Sprint(row)
+}
+
+// ColumnName The letters for a column number: 1 is A, 26 is Z, 27 is AA.
+func ColumnName(column int) string {
+	name := ""
+
+	for column > 0 {
+		column--
+		name = string(rune('A'+column%26)) + name
+		column /= 26
+	}
+
+	return name
+}
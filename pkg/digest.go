@@ -0,0 +1,180 @@
+package pkg
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// A digest is a per-worker summary of how a sync run changed their assignments.  We build it from
+// the changes the sync made, so workers hear about exactly what happened to the calendar.
+
+type WorkerChangeKind string
+
+const (
+	WorkerAdded   WorkerChangeKind = "added"
+	WorkerRemoved WorkerChangeKind = "removed"
+	WorkerMoved   WorkerChangeKind = "moved"
+)
+
+type WorkerChange struct {
+	Kind   WorkerChangeKind
+	Sport  string
+	Role   string
+	Before time.Time // Zero unless Kind is WorkerRemoved or WorkerMoved
+	After  time.Time // Zero unless Kind is WorkerAdded or WorkerMoved
+}
+
+type Digest struct {
+	Worker  string
+	Email   string
+	Changes []WorkerChange
+}
+
+// BuildDigests Get a digest for every worker affected by the changes, keyed by email address.
+// Workers without an email address in the directory are skipped, since we can't reach them anyway.
+func BuildDigests(changes []SyncChange, nameToEmailMap map[string]string) map[string]*Digest {
+	digests := make(map[string]*Digest)
+
+	add := func(worker string, change WorkerChange) {
+		email := nameToEmailMap[worker]
+		if email == "" {
+			return
+		}
+
+		digest := digests[email]
+		if digest == nil {
+			digest = &Digest{Worker: worker, Email: email}
+			digests[email] = digest
+		}
+
+		digest.Changes = append(digest.Changes, change)
+	}
+
+	for _, pair := range pairSyncChanges(changes) {
+		before := roleAssignments(pair.Before.Roles)
+		after := roleAssignments(pair.After.Roles)
+		moved := !pair.Before.Datetime.IsZero() && !pair.After.Datetime.IsZero() &&
+			!pair.Before.Datetime.Equal(pair.After.Datetime)
+
+		for assignment := range before {
+			if !after[assignment] {
+				add(assignment.worker, WorkerChange{
+					Kind: WorkerRemoved, Sport: pair.Before.Sport, Role: assignment.role, Before: pair.Before.Datetime,
+				})
+			} else if moved {
+				add(assignment.worker, WorkerChange{
+					Kind:   WorkerMoved,
+					Sport:  pair.After.Sport,
+					Role:   assignment.role,
+					Before: pair.Before.Datetime,
+					After:  pair.After.Datetime,
+				})
+			}
+		}
+
+		for assignment := range after {
+			if !before[assignment] {
+				add(assignment.worker, WorkerChange{
+					Kind: WorkerAdded, Sport: pair.After.Sport, Role: assignment.role, After: pair.After.Datetime,
+				})
+			}
+		}
+	}
+
+	for _, digest := range digests {
+		sort.Slice(digest.Changes, func(i, j int) bool {
+			first, second := digest.Changes[i], digest.Changes[j]
+			if !first.when().Equal(second.when()) {
+				return first.when().Before(second.when())
+			}
+
+			return first.Role < second.Role
+		})
+	}
+
+	return digests
+}
+
+func (change WorkerChange) when() time.Time {
+	if change.After.IsZero() {
+		return change.Before
+	}
+
+	return change.After
+}
+
+type syncChangePair struct {
+	Before SportingEvent
+	After  SportingEvent
+}
+
+// pairSyncChanges The event key includes the start time, so a game that moves shows up as a delete of
+// the old event and a create of the new one.  Put those back together (same sport on the same day)
+// so the workers hear "your game moved" instead of "you were removed" and "you were added".
+func pairSyncChanges(changes []SyncChange) []syncChangePair {
+	var pairs []syncChangePair
+
+	var deletes []SyncChange
+
+	for _, change := range changes {
+		if change.Err != nil {
+			continue // The calendar didn't change, so there's nothing to tell anyone.
+		}
+
+		switch change.Action {
+		case SyncUpdate:
+			pairs = append(pairs, syncChangePair{Before: change.Before, After: change.After})
+		case SyncDelete:
+			deletes = append(deletes, change)
+		}
+	}
+
+	for _, change := range changes {
+		if change.Err != nil || change.Action != SyncCreate {
+			continue
+		}
+
+		pair := syncChangePair{After: change.After}
+
+		for index, deleted := range deletes {
+			if deleted.Before.Sport == change.After.Sport && sameDay(deleted.Before.Datetime, change.After.Datetime) {
+				pair.Before = deleted.Before
+				deletes = append(deletes[:index], deletes[index+1:]...)
+
+				break
+			}
+		}
+
+		pairs = append(pairs, pair)
+	}
+
+	for _, deleted := range deletes {
+		pairs = append(pairs, syncChangePair{Before: deleted.Before})
+	}
+
+	return pairs
+}
+
+func sameDay(first time.Time, second time.Time) bool {
+	firstYear, firstMonth, firstDay := first.Date()
+	secondYear, secondMonth, secondDay := second.In(first.Location()).Date()
+
+	return firstYear == secondYear && firstMonth == secondMonth && firstDay == secondDay
+}
+
+type roleAssignment struct {
+	role   string
+	worker string
+}
+
+func roleAssignments(roles []string) map[roleAssignment]bool {
+	assignments := make(map[roleAssignment]bool)
+
+	for _, role := range roles {
+		roleName, worker := SplitRole(role)
+		assignments[roleAssignment{role: roleName, worker: strings.TrimSpace(worker)}] = true
+	}
+
+	return assignments
+}
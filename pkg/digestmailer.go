@@ -0,0 +1,153 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// The digest templates are given a digestData.  The organization is optional, so the defaults read
+// naturally without one.
+const defaultDigestSubject = "Your {{with .Organization}}{{.}} {{end}}game day assignments have changed"
+
+const defaultDigestTextTemplate = `Hi {{.Worker}},
+
+The {{with .Organization}}{{.}} {{end}}game day worker schedule has changed:
+{{range .Changes}}
+{{- if eq .Kind "added"}}
+  * ADDED: {{.Role}} for {{.Sport}} on {{formatTime .After}}
+{{- else if eq .Kind "removed"}}
+  * REMOVED: {{.Role}} for {{.Sport}} on {{formatTime .Before}}
+{{- else if eq .Kind "moved"}}
+  * MOVED: {{.Role}} for {{.Sport}} from {{formatTime .Before}} to {{formatTime .After}}
+{{- end}}
+{{- end}}
+
+Please check the schedule spreadsheet if you have any questions.
+`
+
+const defaultDigestHTMLTemplate = `<p>Hi {{.Worker}},</p>
+<p>The {{with .Organization}}{{.}} {{end}}game day worker schedule has changed:</p>
+<ul>
+{{- range .Changes}}
+{{- if eq .Kind "added"}}
+  <li><b>Added:</b> {{.Role}} for {{.Sport}} on {{formatTime .After}}</li>
+{{- else if eq .Kind "removed"}}
+  <li><b>Removed:</b> {{.Role}} for {{.Sport}} on {{formatTime .Before}}</li>
+{{- else if eq .Kind "moved"}}
+  <li><b>Moved:</b> {{.Role}} for {{.Sport}} from {{formatTime .Before}} to {{formatTime .After}}</li>
+{{- end}}
+{{- end}}
+</ul>
+<p>Please check the schedule spreadsheet if you have any questions.</p>
+`
+
+// digestData A digest, and the tenant's settings the templates can use.
+type digestData struct {
+	*Digest
+	Organization string
+}
+
+type DigestMailer struct {
+	smtpConfig   SMTPConfiguration
+	organization string
+	optOut       map[string]bool
+	subject      *texttemplate.Template
+	textTemplate *texttemplate.Template
+	htmlTemplate *htmltemplate.Template
+}
+
+func NewDigestMailer(smtpConfig SMTPConfiguration, digestConfig DigestConfiguration) (*DigestMailer, error) {
+	funcs := map[string]any{"formatTime": formatFriendlyTime}
+
+	subjectSource := digestConfig.Subject
+	if subjectSource == "" {
+		subjectSource = defaultDigestSubject
+	}
+
+	subject, err := texttemplate.New("subject").Funcs(funcs).Parse(subjectSource)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest subject: %w", err)
+	}
+
+	textSource, err := readTemplate(digestConfig.TextTemplate, defaultDigestTextTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	textTemplate, err := texttemplate.New("digest.txt").Funcs(funcs).Parse(textSource)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest text template: %w", err)
+	}
+
+	htmlSource, err := readTemplate(digestConfig.HTMLTemplate, defaultDigestHTMLTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlTemplate, err := htmltemplate.New("digest.html").Funcs(funcs).Parse(htmlSource)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest HTML template: %w", err)
+	}
+
+	optOut := make(map[string]bool)
+	for _, worker := range digestConfig.OptOut {
+		optOut[strings.ToLower(worker)] = true
+	}
+
+	return &DigestMailer{
+		smtpConfig:   smtpConfig,
+		organization: digestConfig.Organization,
+		optOut:       optOut,
+		subject:      subject,
+		textTemplate: textTemplate,
+		htmlTemplate: htmlTemplate,
+	}, nil
+}
+
+// IsOptedOut Workers can opt out by name or by email address.
+func (mailer *DigestMailer) IsOptedOut(digest *Digest) bool {
+	return mailer.optOut[strings.ToLower(digest.Worker)] || mailer.optOut[strings.ToLower(digest.Email)]
+}
+
+func (mailer *DigestMailer) Send(digest *Digest) error {
+	var subject, textBody, htmlBody bytes.Buffer
+
+	data := digestData{Digest: digest, Organization: mailer.organization}
+
+	if err := mailer.subject.Execute(&subject, data); err != nil {
+		return fmt.Errorf("unable to render digest for %s: %w", digest.Email, err)
+	}
+
+	if err := mailer.textTemplate.Execute(&textBody, data); err != nil {
+		return fmt.Errorf("unable to render digest for %s: %w", digest.Email, err)
+	}
+
+	if err := mailer.htmlTemplate.Execute(&htmlBody, data); err != nil {
+		return fmt.Errorf("unable to render digest for %s: %w", digest.Email, err)
+	}
+
+	return SendEmail(mailer.smtpConfig, digest.Email, subject.String(), textBody.String(), htmlBody.String())
+}
+
+// readTemplate Use the template file if one is configured, otherwise the built-in template.
+func readTemplate(path string, defaultTemplate string) (string, error) {
+	if path == "" {
+		return defaultTemplate, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read template %s: %w", path, err)
+	}
+
+	return string(content), nil
+}
+
+func formatFriendlyTime(datetime time.Time) string {
+	return datetime.Format("Monday, January 2 at 3:04pm")
+}
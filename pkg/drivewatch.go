@@ -0,0 +1,260 @@
+package pkg
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"google.golang.org/api/drive/v3"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Rather than waiting for the next scheduled sync, we can ask Drive to tell us when the spreadsheet
+// changes.  Drive POSTs to a public HTTPS address that we give it for as long as the watch channel
+// lasts (a day at most), so the channel needs to be renewed before it expires.
+// See https://developers.google.com/drive/api/guides/push for details.
+
+// How long to wait before retrying a failed channel renewal.
+const watchRenewalRetry = 5 * time.Minute
+
+// DriveNotificationReceiver An http.Handler for Drive push notifications.  Editing a spreadsheet
+// generates a burst of notifications, so the trigger is only called once things have been quiet for
+// the debounce period.
+type DriveNotificationReceiver struct {
+	token    string
+	debounce time.Duration
+	trigger  func()
+	logger   *slog.Logger
+
+	mutex      sync.Mutex
+	channelIDs map[string]bool
+	timer      *time.Timer
+}
+
+// NewDriveNotificationReceiver If token is empty, a random one is used.
+func NewDriveNotificationReceiver(
+	token string,
+	debounce time.Duration,
+	trigger func(),
+	logger *slog.Logger) *DriveNotificationReceiver {
+	if token == "" {
+		token = randomHex()
+	}
+
+	return &DriveNotificationReceiver{
+		token:      token,
+		debounce:   debounce,
+		trigger:    trigger,
+		logger:     logger,
+		channelIDs: make(map[string]bool),
+	}
+}
+
+// AcceptChannel Start accepting notifications for a channel.  While a channel is being renewed,
+// notifications can arrive on both the old and the new channel.
+func (receiver *DriveNotificationReceiver) AcceptChannel(channelID string) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+
+	receiver.channelIDs[channelID] = true
+}
+
+func (receiver *DriveNotificationReceiver) RejectChannel(channelID string) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+
+	delete(receiver.channelIDs, channelID)
+}
+
+func (receiver *DriveNotificationReceiver) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(writer, "POST only", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	channelID := request.Header.Get("X-Goog-Channel-ID")
+	token := request.Header.Get("X-Goog-Channel-Token")
+	state := request.Header.Get("X-Goog-Resource-State")
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(receiver.token)) != 1 {
+		receiver.logger.Warn("Rejecting Drive notification with a bad token", "channel_id", channelID)
+		http.Error(writer, "bad channel token", http.StatusForbidden)
+
+		return
+	}
+
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+
+	if !receiver.channelIDs[channelID] {
+		// Returning an error tells Drive to stop sending on this channel, which is what we want for a
+		// channel we have already replaced.
+		http.Error(writer, "unknown channel", http.StatusNotFound)
+
+		return
+	}
+
+	// Drive sends a "sync" notification when the channel is created.  Nothing has changed yet.
+	if state != "sync" {
+		if receiver.timer == nil {
+			receiver.timer = time.AfterFunc(receiver.debounce, receiver.fire)
+		} else {
+			receiver.timer.Reset(receiver.debounce)
+		}
+	}
+
+	writer.WriteHeader(http.StatusOK)
+}
+
+func (receiver *DriveNotificationReceiver) fire() {
+	receiver.mutex.Lock()
+	receiver.timer = nil
+	receiver.mutex.Unlock()
+
+	receiver.trigger()
+}
+
+// DriveWatcher Keeps a Drive watch channel open on a file, renewing it before it expires.
+type DriveWatcher struct {
+	driveService *drive.Service
+	fileID       string
+	config       WatchConfiguration
+	receiver     *DriveNotificationReceiver
+	logger       *slog.Logger
+
+	mutex   sync.Mutex
+	channel *drive.Channel
+}
+
+func NewDriveWatcher(
+	driveService *drive.Service,
+	fileID string,
+	config WatchConfiguration,
+	receiver *DriveNotificationReceiver,
+	logger *slog.Logger) *DriveWatcher {
+	return &DriveWatcher{driveService: driveService, fileID: fileID, config: config, receiver: receiver, logger: logger}
+}
+
+// Start Open the first watch channel.
+func (watcher *DriveWatcher) Start() error {
+	channel, err := watcher.openChannel()
+	if err != nil {
+		return err
+	}
+
+	watcher.mutex.Lock()
+	watcher.channel = channel
+	watcher.mutex.Unlock()
+
+	return nil
+}
+
+// Active Report whether we expect to be getting notifications right now.
+func (watcher *DriveWatcher) Active() bool {
+	return time.Now().Before(watcher.Expiration())
+}
+
+func (watcher *DriveWatcher) Expiration() time.Time {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	if watcher.channel == nil {
+		return time.Time{}
+	}
+
+	return time.UnixMilli(watcher.channel.Expiration)
+}
+
+// RenewUntilDone Replace the channel shortly before it expires, until the context is done.
+// If renewal keeps failing the channel expires, Active reports false, and callers fall back to polling.
+func (watcher *DriveWatcher) RenewUntilDone(ctx context.Context) {
+	for {
+		renewAt := watcher.Expiration().Add(-watcher.config.RenewBefore)
+		timer := time.NewTimer(time.Until(renewAt))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return
+		case <-timer.C:
+		}
+
+		if err := watcher.renew(); err != nil {
+			watcher.logger.Error("Unable to renew the Drive watch channel", "retry_in", watchRenewalRetry, "error", err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(watchRenewalRetry):
+			}
+		}
+	}
+}
+
+// Stop Close the channel so Drive stops sending notifications.
+func (watcher *DriveWatcher) Stop() {
+	watcher.mutex.Lock()
+	channel := watcher.channel
+	watcher.channel = nil
+	watcher.mutex.Unlock()
+
+	if channel != nil {
+		watcher.closeChannel(channel)
+	}
+}
+
+func (watcher *DriveWatcher) renew() error {
+	channel, err := watcher.openChannel()
+	if err != nil {
+		return err
+	}
+
+	watcher.mutex.Lock()
+	oldChannel := watcher.channel
+	watcher.channel = channel
+	watcher.mutex.Unlock()
+
+	if oldChannel != nil {
+		watcher.closeChannel(oldChannel)
+	}
+
+	return nil
+}
+
+func (watcher *DriveWatcher) openChannel() (*drive.Channel, error) {
+	request := &drive.Channel{
+		Id:         randomHex(),
+		Type:       "web_hook",
+		Address:    watcher.config.Address,
+		Token:      watcher.receiver.token,
+		Expiration: time.Now().Add(watcher.config.ChannelTTL).UnixMilli(),
+	}
+
+	// Accept notifications before asking for them, since Drive sends a sync notification right away.
+	watcher.receiver.AcceptChannel(request.Id)
+
+	channel, err := watcher.driveService.Files.Watch(watcher.fileID, request).Do()
+	if err != nil {
+		watcher.receiver.RejectChannel(request.Id)
+
+		return nil, fmt.Errorf("unable to watch file %s: %w", watcher.fileID, err)
+	}
+
+	watcher.logger.Info("Watching for spreadsheet changes",
+		"channel_id", channel.Id, "expiration", time.UnixMilli(channel.Expiration).Format(time.RFC3339))
+
+	return channel, nil
+}
+
+func (watcher *DriveWatcher) closeChannel(channel *drive.Channel) {
+	watcher.receiver.RejectChannel(channel.Id)
+
+	err := watcher.driveService.Channels.Stop(&drive.Channel{Id: channel.Id, ResourceId: channel.ResourceId}).Do()
+	if err != nil {
+		watcher.logger.Warn("Unable to stop the Drive watch channel", "channel_id", channel.Id, "error", err)
+	}
+}
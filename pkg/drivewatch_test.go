@@ -0,0 +1,144 @@
+package pkg
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testChannelToken = "channel-token"
+
+// newTestReceiver A receiver accepting channel "known", served on a local address so the tests can
+// play the part of Drive.  It returns the server and a count of the triggers.
+func newTestReceiver(t *testing.T, debounce time.Duration) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var triggers atomic.Int32
+
+	receiver := NewDriveNotificationReceiver(testChannelToken, debounce, func() { triggers.Add(1) }, slog.Default())
+	receiver.AcceptChannel("known")
+
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	return server, &triggers
+}
+
+// sendNotification Send a notification the way Drive does, and return the status code.
+func sendNotification(t *testing.T, server *httptest.Server, channelID string, token string, state string) int {
+	t.Helper()
+
+	request, err := http.NewRequest(http.MethodPost, server.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+
+	request.Header.Set("X-Goog-Channel-ID", channelID)
+	request.Header.Set("X-Goog-Channel-Token", token)
+	request.Header.Set("X-Goog-Resource-State", state)
+
+	response, err := server.Client().Do(request)
+	if err != nil {
+		t.Fatalf("sending notification: %v", err)
+	}
+	defer response.Body.Close()
+
+	return response.StatusCode
+}
+
+func TestDriveNotificationReceiverResponses(t *testing.T) {
+	tests := []struct {
+		name      string
+		channelID string
+		token     string
+		state     string
+		want      int
+	}{
+		{"bad token", "known", "wrong-token", "update", http.StatusForbidden},
+		{"no token", "known", "", "update", http.StatusForbidden},
+		{"unknown channel", "replaced", testChannelToken, "update", http.StatusNotFound},
+		{"sync", "known", testChannelToken, "sync", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, triggers := newTestReceiver(t, 10*time.Millisecond)
+
+			if status := sendNotification(t, server, test.channelID, test.token, test.state); status != test.want {
+				t.Errorf("status = %d, want %d", status, test.want)
+			}
+
+			time.Sleep(50 * time.Millisecond)
+
+			if count := triggers.Load(); count != 0 {
+				t.Errorf("triggered %d times, want none", count)
+			}
+		})
+	}
+}
+
+func TestDriveNotificationReceiverRejectsGet(t *testing.T) {
+	server, _ := newTestReceiver(t, 10*time.Millisecond)
+
+	response, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", response.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+// Editing a spreadsheet sends a burst of notifications, which should only sync once.
+func TestDriveNotificationReceiverDebounces(t *testing.T) {
+	const debounce = 200 * time.Millisecond
+
+	server, triggers := newTestReceiver(t, debounce)
+
+	for _, state := range []string{"update", "update", "change", "update", "update"} {
+		if status := sendNotification(t, server, "known", testChannelToken, state); status != http.StatusOK {
+			t.Fatalf("status = %d, want %d", status, http.StatusOK)
+		}
+	}
+
+	if count := triggers.Load(); count != 0 {
+		t.Errorf("triggered %d times during the burst, want none", count)
+	}
+
+	time.Sleep(3 * debounce)
+
+	if count := triggers.Load(); count != 1 {
+		t.Errorf("triggered %d times after the burst, want once", count)
+	}
+
+	// Once it's quiet, the next edit triggers again.
+	sendNotification(t, server, "known", testChannelToken, "update")
+	time.Sleep(3 * debounce)
+
+	if count := triggers.Load(); count != 2 {
+		t.Errorf("triggered %d times after a second edit, want twice", count)
+	}
+}
+
+func TestDriveNotificationReceiverRejectChannel(t *testing.T) {
+	receiver := NewDriveNotificationReceiver(testChannelToken, time.Millisecond, func() {}, slog.Default())
+	receiver.AcceptChannel("old")
+	receiver.AcceptChannel("new")
+	receiver.RejectChannel("old")
+
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	if status := sendNotification(t, server, "old", testChannelToken, "update"); status != http.StatusNotFound {
+		t.Errorf("old channel status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	if status := sendNotification(t, server, "new", testChannelToken, "update"); status != http.StatusOK {
+		t.Errorf("new channel status = %d, want %d", status, http.StatusOK)
+	}
+}
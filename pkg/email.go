@@ -0,0 +1,123 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultSMTPPort = 25
+
+// SendEmail Send a message with both a plain text and an HTML body.  Pointing the configuration at a
+// local SMTP stand-in (e.g. MailHog on port 1025) is the easiest way to see what would be sent.
+func SendEmail(config SMTPConfiguration, toAddress string, subject string, textBody string, htmlBody string) error {
+	if config.Host == "" {
+		return fmt.Errorf("no SMTP host is configured")
+	}
+
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address %q: %w", config.From, err)
+	}
+
+	to, err := mail.ParseAddress(toAddress)
+	if err != nil {
+		return fmt.Errorf("invalid to address %q: %w", toAddress, err)
+	}
+
+	message, err := buildMessage(from, to, subject, textBody, htmlBody)
+	if err != nil {
+		return err
+	}
+
+	port := config.Port
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+
+	address := net.JoinHostPort(config.Host, strconv.Itoa(port))
+
+	err = smtp.SendMail(address, auth, from.Address, []string{to.Address}, message)
+	if err != nil {
+		return fmt.Errorf("unable to send email to %s via %s: %w", to.Address, address, err)
+	}
+
+	return nil
+}
+
+func buildMessage(from *mail.Address, to *mail.Address, subject string, textBody string, htmlBody string) ([]byte, error) {
+	var body bytes.Buffer
+
+	writer := multipart.NewWriter(&body)
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", textBody},
+		{"text/html; charset=UTF-8", htmlBody},
+	}
+
+	for _, part := range parts {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+		partWriter, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := quotedprintable.NewWriter(partWriter)
+		if _, err = encoder.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+
+		if err = encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+
+	fmt.Fprintf(&message, "From: %s\r\n", from.String())
+	fmt.Fprintf(&message, "To: %s\r\n", to.String())
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "Message-ID: <%s@%s>\r\n", randomHex(), from.Address[strings.LastIndex(from.Address, "@")+1:])
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%s\r\n", writer.Boundary())
+	fmt.Fprintf(&message, "\r\n")
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+func randomHex() string {
+	const randomBytes = 16
+
+	value := make([]byte, randomBytes)
+	_, _ = rand.Read(value)
+
+	return hex.EncodeToString(value)
+}
@@ -0,0 +1,323 @@
+package pkg
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// receivedEmail What the fake SMTP server was sent.
+type receivedEmail struct {
+	from    string
+	to      []string
+	message string
+}
+
+// startFakeSMTPServer Accept one connection on a local port and record the message it delivers.  Just
+// enough SMTP for net/smtp, with no extensions, so no TLS or authentication.
+func startFakeSMTPServer(t *testing.T) (SMTPConfiguration, <-chan receivedEmail) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan receivedEmail, 1)
+
+	go func() {
+		connection, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer connection.Close()
+
+		conversation := textproto.NewConn(connection)
+
+		var email receivedEmail
+
+		_ = conversation.PrintfLine("220 localhost fake SMTP")
+
+		for {
+			line, err := conversation.ReadLine()
+			if err != nil {
+				return
+			}
+
+			command := strings.ToUpper(line)
+
+			switch {
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				email.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				_ = conversation.PrintfLine("250 OK")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				email.to = append(email.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+				_ = conversation.PrintfLine("250 OK")
+			case command == "DATA":
+				_ = conversation.PrintfLine("354 Go ahead")
+
+				message, err := io.ReadAll(conversation.DotReader())
+				if err != nil {
+					return
+				}
+
+				email.message = string(message)
+				_ = conversation.PrintfLine("250 OK")
+			case command == "QUIT":
+				_ = conversation.PrintfLine("221 Bye")
+				received <- email
+
+				return
+			default:
+				// HELO, EHLO, RSET and anything else
+				_ = conversation.PrintfLine("250 OK")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+
+	return SMTPConfiguration{Host: host, Port: portNumber, From: "Game Day <gameday@example.com>"}, received
+}
+
+func waitForEmail(t *testing.T, received <-chan receivedEmail) receivedEmail {
+	t.Helper()
+
+	select {
+	case email := <-received:
+		return email
+	case <-time.After(5 * time.Second):
+		t.Fatal("the fake SMTP server got no message")
+
+		return receivedEmail{}
+	}
+}
+
+// readParts Parse a multipart/alternative message, returning its parts' bodies keyed by content type.
+func readParts(t *testing.T, message *mail.Message) map[string]string {
+	t.Helper()
+
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", message.Header.Get("Content-Type"))
+	}
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(message.Body, params["boundary"])
+
+	for {
+		// NextPart undoes the quoted-printable encoding.
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts
+		}
+
+		if err != nil {
+			t.Fatalf("NextPart: %v", err)
+		}
+
+		content, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("reading part: %v", err)
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[partType] = string(content)
+	}
+}
+
+func TestSendEmail(t *testing.T) {
+	config, received := startFakeSMTPServer(t)
+
+	// Long enough, and with enough non-ASCII, to need quoted-printable soft line breaks.
+	textBody := "Hockey on Saturday — " + strings.Repeat("the quick brown fox ", 10)
+	htmlBody := "<p>Hockey on <b>Saturday</b> — café</p>"
+
+	err := SendEmail(config, "Pat Worker <pat@example.com>", "Schedule — changed", textBody, htmlBody)
+	if err != nil {
+		t.Fatalf("SendEmail: %v", err)
+	}
+
+	email := waitForEmail(t, received)
+
+	if email.from != "gameday@example.com" {
+		t.Errorf("MAIL FROM = %q, want gameday@example.com", email.from)
+	}
+
+	if len(email.to) != 1 || email.to[0] != "pat@example.com" {
+		t.Errorf("RCPT TO = %q, want [pat@example.com]", email.to)
+	}
+
+	message, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(email.message)))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	if err != nil || subject != "Schedule — changed" {
+		t.Errorf("Subject = %q (%v), want %q", subject, err, "Schedule — changed")
+	}
+
+	if to := message.Header.Get("To"); !strings.Contains(to, "pat@example.com") {
+		t.Errorf("To = %q, want pat@example.com", to)
+	}
+
+	if message.Header.Get("MIME-Version") != "1.0" || message.Header.Get("Message-ID") == "" {
+		t.Errorf("MIME-Version = %q, Message-ID = %q, want both set",
+			message.Header.Get("MIME-Version"), message.Header.Get("Message-ID"))
+	}
+
+	parts := readParts(t, message)
+
+	if len(parts) != 2 {
+		t.Errorf("got parts %q, want text/plain and text/html", parts)
+	}
+
+	if parts["text/plain"] != textBody {
+		t.Errorf("text/plain part = %q, want %q", parts["text/plain"], textBody)
+	}
+
+	if parts["text/html"] != htmlBody {
+		t.Errorf("text/html part = %q, want %q", parts["text/html"], htmlBody)
+	}
+}
+
+func TestSendEmailWithoutHost(t *testing.T) {
+	if err := SendEmail(SMTPConfiguration{}, "pat@example.com", "subject", "text", "html"); err == nil {
+		t.Error("SendEmail with no host succeeded, want an error")
+	}
+}
+
+// sendTestDigest Send Pat Worker a one-change digest through a fake SMTP server, and return the message.
+func sendTestDigest(t *testing.T, digestConfig DigestConfiguration) *mail.Message {
+	t.Helper()
+
+	config, received := startFakeSMTPServer(t)
+
+	mailer, err := NewDigestMailer(config, digestConfig)
+	if err != nil {
+		t.Fatalf("NewDigestMailer: %v", err)
+	}
+
+	digest := &Digest{
+		Worker: "Pat Worker",
+		Email:  "pat@example.com",
+		Changes: []WorkerChange{
+			{Kind: WorkerAdded, Sport: "Hockey", Role: "Announcer", After: time.Date(2024, 2, 3, 19, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	if err = mailer.Send(digest); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	message, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(waitForEmail(t, received).message)))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+
+	return message
+}
+
+func TestDigestMailerSend(t *testing.T) {
+	message := sendTestDigest(t, DigestConfiguration{})
+
+	if subject := message.Header.Get("Subject"); subject != "Your game day assignments have changed" {
+		t.Errorf("Subject = %q, want the built-in subject naming no organization", subject)
+	}
+
+	parts := readParts(t, message)
+
+	if text := parts["text/plain"]; !strings.Contains(text, "Hi Pat Worker") ||
+		!strings.Contains(text, "ADDED: Announcer for Hockey") {
+		t.Errorf("text/plain part = %q, want the greeting and the added assignment", text)
+	}
+
+	if html := parts["text/html"]; !strings.Contains(html, "<b>Added:</b> Announcer for Hockey") {
+		t.Errorf("text/html part = %q, want the added assignment", html)
+	}
+}
+
+func TestDigestMailerOrganization(t *testing.T) {
+	tests := []struct {
+		name         string
+		digestConfig DigestConfiguration
+		wantSubject  string
+		wantText     string
+	}{
+		{
+			name:         "built-in subject",
+			digestConfig: DigestConfiguration{Organization: "Brown"},
+			wantSubject:  "Your Brown game day assignments have changed",
+			wantText:     "The Brown game day worker schedule has changed",
+		},
+		{
+			name: "configured subject",
+			digestConfig: DigestConfiguration{
+				Organization: "Bears",
+				Subject:      "{{.Organization}} schedule for {{.Worker}}",
+			},
+			wantSubject: "Bears schedule for Pat Worker",
+			wantText:    "The Bears game day worker schedule has changed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			message := sendTestDigest(t, test.digestConfig)
+
+			if subject := message.Header.Get("Subject"); subject != test.wantSubject {
+				t.Errorf("Subject = %q, want %q", subject, test.wantSubject)
+			}
+
+			if text := readParts(t, message)["text/plain"]; !strings.Contains(text, test.wantText) {
+				t.Errorf("text/plain part = %q, want %q", text, test.wantText)
+			}
+		})
+	}
+}
+
+func TestNewDigestMailerInvalidSubject(t *testing.T) {
+	if _, err := NewDigestMailer(SMTPConfiguration{}, DigestConfiguration{Subject: "{{.Worker"}); err == nil {
+		t.Error("NewDigestMailer with an invalid subject succeeded, want an error")
+	}
+}
+
+func TestDigestMailerIsOptedOut(t *testing.T) {
+	mailer, err := NewDigestMailer(SMTPConfiguration{}, DigestConfiguration{
+		OptOut: []string{"Pat Worker", "Sam@Example.com"},
+	})
+	if err != nil {
+		t.Fatalf("NewDigestMailer: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		worker string
+		email  string
+		want   bool
+	}{
+		{"by name", "Pat Worker", "pat@example.com", true},
+		{"by name, any case", "pat worker", "pat@example.com", true},
+		{"by email, any case", "Sam Worker", "sam@example.com", true},
+		{"not opted out", "Lee Worker", "lee@example.com", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := mailer.IsOptedOut(&Digest{Worker: test.worker, Email: test.email}); got != test.want {
+				t.Errorf("IsOptedOut(%s, %s) = %v, want %v", test.worker, test.email, got, test.want)
+			}
+		})
+	}
+}
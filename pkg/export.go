@@ -0,0 +1,158 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Exports of the parsed spreadsheet, for people who want the schedule somewhere other than the
+// shared calendar.
+
+const (
+	ExportJSON = "json"
+	ExportCSV  = "csv"
+	ExportICS  = "ics"
+)
+
+// ICS lines longer than this many octets have to be folded.
+const icsMaxLineLength = 75
+
+// ExportEvents Write the events in the given format (json, csv or ics).
+func ExportEvents(writer io.Writer, format string, sportingEvents []SportingEvent) error {
+	switch format {
+	case ExportJSON:
+		return exportJSON(writer, sportingEvents)
+	case ExportCSV:
+		return exportCSV(writer, sportingEvents)
+	case ExportICS:
+		return exportICS(writer, sportingEvents, time.Now())
+	default:
+		return fmt.Errorf("unknown export format %q, expected json, csv or ics", format)
+	}
+}
+
+func exportJSON(writer io.Writer, sportingEvents []SportingEvent) error {
+	scheduledEvents := make([]ScheduledEvent, 0, len(sportingEvents))
+	for _, sportingEvent := range sportingEvents {
+		scheduledEvents = append(scheduledEvents, NewScheduledEvent(sportingEvent))
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(scheduledEvents)
+}
+
+// exportCSV One row per assignment, so the export can be filtered by worker in a spreadsheet.  An
+// event with nobody assigned still gets a row.
+func exportCSV(writer io.Writer, sportingEvents []SportingEvent) error {
+	csvWriter := csv.NewWriter(writer)
+
+	if err := csvWriter.Write([]string{"Date", "Time", "Sport", "Location", "Role", "Worker", "Email"}); err != nil {
+		return err
+	}
+
+	for _, sportingEvent := range sportingEvents {
+		eventColumns := []string{
+			sportingEvent.Datetime.Format("2006-01-02"),
+			sportingEvent.Datetime.Format("15:04"),
+			sportingEvent.Sport,
+			sportingEvent.Location,
+		}
+
+		assignments := sportingEvent.Assignments
+		if len(assignments) == 0 {
+			assignments = []Assignment{{}}
+		}
+
+		for _, assignment := range assignments {
+			record := append(append([]string{}, eventColumns...), assignment.Role, assignment.Worker, assignment.Email)
+			if err := csvWriter.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	csvWriter.Flush()
+
+	return csvWriter.Error()
+}
+
+// exportICS An iCalendar file (RFC 5545).  Each event's UID comes from its key, so importing a newer
+// export updates the events from an older one instead of duplicating them.
+func exportICS(writer io.Writer, sportingEvents []SportingEvent, now time.Time) error {
+	const utcLayout = "20060102T150405Z"
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//go-brown-sports//EN",
+		"CALSCALE:GREGORIAN",
+	}
+
+	for _, sportingEvent := range sportingEvents {
+		uid := sha256.Sum256([]byte(sportingEvent.GetKey()))
+		start := sportingEvent.Datetime.UTC()
+
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+hex.EncodeToString(uid[:16])+"@go-brown-sports",
+			"DTSTAMP:"+now.UTC().Format(utcLayout),
+			"DTSTART:"+start.Format(utcLayout),
+			"DTEND:"+start.Add(durationInHours*time.Hour).Format(utcLayout),
+			"SUMMARY:"+escapeICSText(sportingEvent.Sport))
+
+		if sportingEvent.Location != "" {
+			lines = append(lines, "LOCATION:"+escapeICSText(sportingEvent.Location))
+		}
+
+		if len(sportingEvent.Roles) > 0 {
+			lines = append(lines, "DESCRIPTION:"+escapeICSText(strings.Join(sportingEvent.Roles, "\n")))
+		}
+
+		lines = append(lines, "END:VEVENT")
+	}
+
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(writer, foldICSLine(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func escapeICSText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(text)
+}
+
+// foldICSLine Split long lines, continuing each piece on a line starting with a space.  Never split
+// in the middle of a UTF-8 character.
+func foldICSLine(line string) string {
+	var folded strings.Builder
+
+	length := 0
+
+	for _, character := range line {
+		size := len(string(character))
+		if length+size > icsMaxLineLength {
+			folded.WriteString("\r\n ")
+
+			length = 1
+		}
+
+		folded.WriteRune(character)
+
+		length += size
+	}
+
+	return folded.String()
+}
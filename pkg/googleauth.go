@@ -0,0 +1,91 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"net/http"
+	"os"
+)
+
+// How we authenticate to the Google APIs.  The quickstart's installed-app flow needs someone to
+// authorize it in a browser, which doesn't work for a headless host, so a service account or the
+// host's own credentials can be used instead.
+
+const (
+	AuthMethodOAuth          = "oauth"          // A user authorizes us in their browser (the default)
+	AuthMethodServiceAccount = "serviceAccount" // A service account JSON key, optionally impersonating a user
+	AuthMethodDefault        = "default"        // Application default credentials, like workload identity
+)
+
+// newGoogleHTTPClient Get a client with the profiles' scopes, authenticating with the configured method.
+func newGoogleHTTPClient(
+	ctx context.Context,
+	cfg *Config,
+	profiles []string,
+	scopes []string) (*http.Client, error) {
+	config := cfg.GetAuthConfiguration()
+
+	if config.Method == AuthMethodOAuth {
+		oauthConfig, err := LoadOAuthConfig(cfg, scopes)
+		if err != nil {
+			return nil, err
+		}
+
+		return GetClient(cfg, oauthConfig, profiles)
+	}
+
+	tokenSource, err := newCredentialsTokenSource(ctx, config, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	return oauth2.NewClient(ctx, tokenSource), nil
+}
+
+// newCredentialsTokenSource Get tokens for a service account or the application default
+// credentials.  Nobody has to authorize these, so there's no token file, and every token has all
+// the scopes asked for.
+func newCredentialsTokenSource(
+	ctx context.Context,
+	config AuthConfiguration,
+	scopes []string) (oauth2.TokenSource, error) {
+	switch config.Method {
+	case AuthMethodServiceAccount:
+		if config.KeyPath == "" {
+			return nil, errors.New("auth.keyPath must be set to use a service account")
+		}
+
+		key, err := os.ReadFile(config.KeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read service account key: %w", err)
+		}
+
+		jwtConfig, err := google.JWTConfigFromJSON(key, scopes...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse service account key: %w", err)
+		}
+
+		// With domain-wide delegation, the service account acts as this user, so it sees their
+		// calendars and the spreadsheets shared with them.
+		jwtConfig.Subject = config.Subject
+
+		return jwtConfig.TokenSource(ctx), nil
+	case AuthMethodDefault:
+		if config.Subject != "" {
+			return nil, errors.New("auth.subject can only be used with a service account key")
+		}
+
+		credentials, err := google.FindDefaultCredentials(ctx, scopes...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find default credentials: %w", err)
+		}
+
+		return credentials.TokenSource, nil
+	default:
+		return nil, fmt.Errorf("unknown auth method %q, expected %s, %s or %s",
+			config.Method, AuthMethodOAuth, AuthMethodServiceAccount, AuthMethodDefault)
+	}
+}
@@ -5,102 +5,164 @@ package pkg
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"strings"
 )
 
-// GetClient Retrieve a token, saves the token, then returns the generated client.
+// GetClient Get a client with config's scopes from the saved token.  Commands can run unattended, so
+// they never ask the user to authorize; if the token is missing or doesn't have the access the
+// profiles need, the error says how to authorize with auth login.
 // This function originated at the Google quickstart for the Go sheets API.
-func GetClient(config *oauth2.Config) *http.Client {
-	// The file token.json stores the user's access and refresh tokens, and is
-	// created automatically when the authorization flow completes for the first
-	// time.
-	tokFile := "token.json"
-	tok, err := TokenFromFile(tokFile)
+func GetClient(cfg *Config, config *oauth2.Config, profiles []string) (*http.Client, error) {
+	ctx := context.Background()
+
+	// The file token.json stores the user's refresh token and the scopes it was granted, and is
+	// created by auth login.
+	store, err := NewTokenStore(cfg.GetTokenPath(), cfg.GetAuthConfiguration())
+	if err != nil {
+		return nil, err
+	}
+
+	tok, err := store.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("there's no saved token at %s; run %s", store.Path(), loginCommand(cfg, profiles))
+	}
+
+	if err != nil {
+		return nil, err
+	}
 
+	granted, err := getGrantedScopes(ctx, config, store, tok, cfg.GetLogger())
 	if err != nil {
-		tok = GetTokenFromWeb(config)
-		SaveToken(tokFile, tok)
+		return nil, err
 	}
 
-	return config.Client(context.Background(), tok)
+	if missing := MissingScopes(granted, config.Scopes); len(missing) > 0 {
+		return nil, fmt.Errorf("the saved token doesn't have all the access this command needs (%s); run %s",
+			strings.Join(missing, " "), loginCommand(cfg, profiles))
+	}
+
+	return oauth2.NewClient(ctx, NewPersistingTokenSource(ctx, config, store, tok, cfg.GetLogger())), nil
 }
 
-// SaveToken Saves a token to a file path.
-// This function originated at the Google quickstart for the Go sheets API.
-func SaveToken(path string, token *oauth2.Token) {
-	log.Printf("Saving credential file to: %s\n", path)
-	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+// loginCommand The command that authorizes the profiles.
+func loginCommand(cfg *Config, profiles []string) string {
+	command := "go-brown-sports auth login -profile " + strings.Join(profiles, ",")
+
+	if name := cfg.GetTenantName(); name != "" {
+		command += " -tenant " + name
+	}
+
+	return command
+}
+
+// getGrantedScopes The scopes the saved token has.  Tokens saved before we kept track of their
+// scopes are looked up once, with an access token that has everything granted, and saved with them.
+func getGrantedScopes(
+	ctx context.Context,
+	config *oauth2.Config,
+	store *TokenStore,
+	tok *oauth2.Token,
+	logger *slog.Logger) ([]string, error) {
+	if granted := TokenScopes(tok); len(granted) > 0 {
+		return granted, nil
+	}
+
+	everything := *config
+	everything.Scopes = nil
 
+	current, err := NewPersistingTokenSource(ctx, &everything, store, tok, logger).Token()
 	if err != nil {
-		log.Fatalf("Unable to cache oauth token: %v", err)
+		return nil, err
 	}
-	defer f.Close()
-	err = json.NewEncoder(f).Encode(token)
 
+	granted, err := FetchGrantedScopes(ctx, current)
 	if err != nil {
-		log.Fatalf("Failure encoding token: %v", err)
+		return nil, err
+	}
+
+	if err = store.Save(withScopes(tok, granted)); err != nil {
+		logger.Error("Unable to save the token's scopes", "file", store.Path(), "error", err)
 	}
+
+	return granted, nil
 }
 
-// GetTokenFromWeb Request a token from the web, then returns the retrieved token.
+// SaveToken Saves a token to a file path, encrypted if there's a token key.
 // This function originated at the Google quickstart for the Go sheets API.
-func GetTokenFromWeb(config *oauth2.Config) *oauth2.Token {
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	log.Printf("Go to the following link in your browser then type the "+
-		"authorization code ('code=' contained within the generated URL): \n%v\n", authURL)
+func SaveToken(path string, auth AuthConfiguration, token *oauth2.Token, logger *slog.Logger) error {
+	logger.Info("Saving credential file", "file", path)
 
-	var authCode string
-	if _, err := fmt.Scan(&authCode); err != nil {
-		log.Fatalf("Unable to read authorization code: %v", err)
+	store, err := NewTokenStore(path, auth)
+	if err != nil {
+		return err
 	}
 
-	tok, err := config.Exchange(context.TODO(), authCode)
-	if err != nil {
-		log.Fatalf("Unable to retrieve token from web: %v", err)
+	return store.Save(token)
+}
+
+// GetTokenFromWeb Ask the user to authorize us with auth's flow, then return the token.
+// This function originated at the Google quickstart for the Go sheets API.
+func GetTokenFromWeb(ctx context.Context, config *oauth2.Config, auth AuthConfiguration) (*oauth2.Token, error) {
+	if err := CheckAuthFlow(auth.Flow); err != nil {
+		return nil, err
 	}
 
-	return tok
+	return loopbackFlowToken(ctx, config, auth.LoopbackPort)
 }
 
 // TokenFromFile Retrieves a token from a local file.
 // This function originated at the Google quickstart for the Go sheets API.
-func TokenFromFile(file string) (*oauth2.Token, error) {
-	fileHandle, err := os.Open(file)
+func TokenFromFile(file string, auth AuthConfiguration) (*oauth2.Token, error) {
+	store, err := NewTokenStore(file, auth)
 	if err != nil {
 		return nil, err
 	}
-	defer fileHandle.Close()
 
-	tok := &oauth2.Token{}
-	err = json.NewDecoder(fileHandle).Decode(tok)
+	return store.Load()
+}
+
+// LoadOAuthConfig Read the OAuth client from the credentials file, asking for the given scopes.
+func LoadOAuthConfig(cfg *Config, scopes []string) (*oauth2.Config, error) {
+	fileHandle, err := os.ReadFile(cfg.GetCredentialsPath())
+	if err != nil {
+		return nil, fmt.Errorf("unable to read client secret file: %w", err)
+	}
+
+	config, err := google.ConfigFromJSON(fileHandle, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse client secret file to config: %w", err)
+	}
 
-	return tok, err
+	return config, nil
 }
 
-func AccessGoogleClient() (context.Context, *http.Client, error) {
+// AccessGoogleClient Get a client with the access the profiles need.
+func AccessGoogleClient(cfg *Config, profiles ...string) (context.Context, *http.Client, error) {
 	ctx := context.Background()
-	fileHandle, err := os.ReadFile("credentials.json")
 
+	scopes, err := ScopesFor(profiles...)
 	if err != nil {
-		log.Fatalf("Unable to read client secret file: %v", err)
+		return nil, nil, err
 	}
 
-	// If modifying these scopes, delete your previously saved token.json.
-	config, err := google.ConfigFromJSON(
-		fileHandle,
-		"https://www.googleapis.com/auth/spreadsheets.readonly",
-		"https://www.googleapis.com/auth/calendar.events")
+	client, err := newGoogleHTTPClient(ctx, cfg, profiles, scopes)
 	if err != nil {
-		log.Fatalf("Unable to parse client secret file to config: %v", err)
+		return nil, nil, err
 	}
 
-	client := GetClient(config)
+	// Metrics for every request, and a span for every request made with a span's context.
+	client.Transport = otelhttp.NewTransport(InstrumentedTransport(cfg.GetTenantName(), client.Transport),
+		otelhttp.WithSpanNameFormatter(func(_ string, request *http.Request) string {
+			return googleAPIMethod(request)
+		}))
 
-	return ctx, client, err
+	return ctx, client, nil
 }
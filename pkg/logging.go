@@ -0,0 +1,46 @@
+package pkg
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// Everything is logged through log/slog with structured fields, so the logs can be searched and fed
+// into a log pipeline.  Fields used across the program:
+//   run_id            - The sync run, as recorded in the audit log
+//   tab, row          - Where on the spreadsheet
+//   event_key         - The datetime+sport key of an event
+//   calendar          - The name of the calendar route
+//   calendar_event_id - The Google Calendar event ID
+//   worker            - A worker's name
+
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// ConfigureLogging Set up the default logger.  Anything still using the log package goes through it too.
+func ConfigureLogging(config LoggingConfiguration) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(config.Level)); err != nil {
+		return fmt.Errorf("invalid log level %q, expected debug, info, warn or error", config.Level)
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+
+	switch config.Format {
+	case LogFormatText:
+		handler = slog.NewTextHandler(os.Stderr, options)
+	case LogFormatJSON:
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	default:
+		return fmt.Errorf("invalid log format %q, expected text or json", config.Format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+
+	return nil
+}
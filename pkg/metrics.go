@@ -0,0 +1,164 @@
+package pkg
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Prometheus metrics for sync runs and the Google API calls they make.  The serve command exposes
+// them on /metrics, so alerts can fire when the sync stops working without anybody noticing.  Every
+// metric has a tenant label, which is empty without tenants.
+
+const metricsNamespace = "go_brown_sports"
+
+var (
+	runDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "sync_run_duration_seconds",
+		Help:      "How long sync runs take, by result (success, failed or skipped).",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 10),
+	}, []string{"tenant", "result"})
+
+	calendarChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "calendar_changes_total",
+		Help:      "Calendar events created, updated or deleted, by action and result (success or failed).",
+	}, []string{"tenant", "action", "result"})
+
+	parseErrors = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "spreadsheet_parse_errors",
+		Help:      "Problems found in each tab of the spreadsheet by the last full run.",
+	}, []string{"tenant", "tab"})
+
+	unknownWorkers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "unknown_workers",
+		Help:      "Workers on the spreadsheet who aren't in the Worker Contact Info tab, as of the last full run.",
+	}, []string{"tenant"})
+
+	lastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "last_success_timestamp_seconds",
+		Help:      "When the last successful run finished, in seconds since the Unix epoch.",
+	}, []string{"tenant"})
+
+	// The histogram's _count series is the number of calls.
+	googleAPIDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "google_api_request_duration_seconds",
+		Help:      "Google API calls, by API method and HTTP status code (or \"error\").",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"tenant", "method", "status"})
+)
+
+// ObserveRun Record the outcome of a tenant's sync run.  diagnostics is nil if the run didn't read the
+// spreadsheet.
+func ObserveRun(tenant string, status RunStatus, changes []SyncChange, diagnostics *Diagnostics) {
+	result := "success"
+
+	switch {
+	case status.Error != "" || status.Failed > 0:
+		result = "failed"
+	case status.Skipped:
+		result = "skipped"
+	}
+
+	runDuration.WithLabelValues(tenant, result).Observe(status.Finished.Sub(status.Started).Seconds())
+
+	if result != "failed" {
+		lastSuccess.WithLabelValues(tenant).Set(float64(status.Finished.Unix()))
+	}
+
+	for _, change := range changes {
+		if change.Action == SyncExternal {
+			continue
+		}
+
+		changeResult := "success"
+		if change.Err != nil {
+			changeResult = "failed"
+		}
+
+		calendarChanges.WithLabelValues(tenant, string(change.Action), changeResult).Inc()
+	}
+
+	if diagnostics != nil {
+		parseErrors.DeletePartialMatch(prometheus.Labels{"tenant": tenant})
+
+		for _, problem := range diagnostics.Problems {
+			parseErrors.WithLabelValues(tenant, problem.Tab).Inc()
+		}
+
+		unknownWorkers.WithLabelValues(tenant).Set(float64(len(diagnostics.UnknownWorkers)))
+	}
+}
+
+// MetricsHandler Serve the metrics in the Prometheus text format.
+func MetricsHandler() http.Handler {
+	return promhttp.Handler()
+}
+
+// InstrumentedTransport Wrap an http.RoundTripper to record a tenant's Google API calls.
+func InstrumentedTransport(tenant string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		started := time.Now()
+		response, err := next.RoundTrip(request)
+
+		status := "error"
+		if err == nil {
+			status = strconv.Itoa(response.StatusCode)
+		}
+
+		googleAPIDuration.WithLabelValues(tenant, googleAPIMethod(request), status).
+			Observe(time.Since(started).Seconds())
+
+		return response, err
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (function roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return function(request)
+}
+
+// The API methods we call, recognized by HTTP method and path.  IDs in the path would make a new
+// label value for every event, so anything else is labeled with just the host and HTTP method.
+var googleAPIMethods = []struct {
+	httpMethod string
+	path       *regexp.Regexp
+	name       string
+}{
+	{http.MethodGet, regexp.MustCompile(`^/calendar/v3/calendars/[^/]+/events$`), "calendar.events.list"},
+	{http.MethodPost, regexp.MustCompile(`^/calendar/v3/calendars/[^/]+/events$`), "calendar.events.insert"},
+	{http.MethodGet, regexp.MustCompile(`^/calendar/v3/calendars/[^/]+/events/[^/]+$`), "calendar.events.get"},
+	{http.MethodPut, regexp.MustCompile(`^/calendar/v3/calendars/[^/]+/events/[^/]+$`), "calendar.events.update"},
+	{http.MethodPatch, regexp.MustCompile(`^/calendar/v3/calendars/[^/]+/events/[^/]+$`), "calendar.events.patch"},
+	{http.MethodDelete, regexp.MustCompile(`^/calendar/v3/calendars/[^/]+/events/[^/]+$`), "calendar.events.delete"},
+	{http.MethodGet, regexp.MustCompile(`^/v4/spreadsheets/[^/]+/values/[^/]+$`), "sheets.spreadsheets.values.get"},
+	{http.MethodGet, regexp.MustCompile(`^/v4/spreadsheets/[^/:]+$`), "sheets.spreadsheets.get"},
+	{http.MethodPost, regexp.MustCompile(`^/v4/spreadsheets/[^/]+:batchUpdate$`), "sheets.spreadsheets.batchUpdate"},
+	{http.MethodGet, regexp.MustCompile(`^/drive/v3/files/[^/]+$`), "drive.files.get"},
+	{http.MethodPost, regexp.MustCompile(`^/drive/v3/files/[^/]+/watch$`), "drive.files.watch"},
+	{http.MethodPost, regexp.MustCompile(`^/drive/v3/channels/stop$`), "drive.channels.stop"},
+}
+
+func googleAPIMethod(request *http.Request) string {
+	for _, method := range googleAPIMethods {
+		if request.Method == method.httpMethod && method.path.MatchString(request.URL.EscapedPath()) {
+			return method.name
+		}
+	}
+
+	return request.URL.Host + " " + request.Method
+}
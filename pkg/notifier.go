@@ -0,0 +1,200 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Notifiers post a single message to a chat channel (or any webhook) for each sync run that changed
+// the calendar, so the communications staff sees changes as they happen.
+
+const (
+	NotifierSlack   = "slack"
+	NotifierDiscord = "discord"
+	NotifierWebhook = "webhook"
+)
+
+const defaultNotificationTemplate = `Game day calendar sync: {{.Created}} added, {{.Updated}} updated, {{.Deleted}} removed
+{{- if .Failed}} ({{.Failed}} failed){{end}}
+{{- range .Entries}}
+- {{.Action}} {{.EventKey}}
+{{- range .Changes}}
+    {{.Field}}: {{if .Before}}{{.Before}}{{else}}(none){{end}} -> {{if .After}}{{.After}}{{else}}(none){{end}}
+{{- end}}
+{{- if .Error}}
+    FAILED: {{.Error}}
+{{- end}}
+{{- end}}
+`
+
+// Discord rejects messages longer than this.
+const discordMaxContentLength = 2000
+
+// Slack rejects messages longer than this.
+const slackMaxTextLength = 40000
+
+const (
+	notifierAttempts     = 3
+	notifierInitialDelay = 2 * time.Second
+	notifierTimeout      = 30 * time.Second
+)
+
+type RunNotification struct {
+	RunID     string       `json:"runId"`
+	Timestamp time.Time    `json:"timestamp"`
+	Created   int          `json:"created"`
+	Updated   int          `json:"updated"`
+	Deleted   int          `json:"deleted"`
+	Failed    int          `json:"failed"`
+	Entries   []AuditEntry `json:"entries"`
+	Text      string       `json:"text"`
+}
+
+type Notifier struct {
+	config   NotifierConfiguration
+	template *texttemplate.Template
+	client   *http.Client
+}
+
+func NewNotifier(config NotifierConfiguration) (*Notifier, error) {
+	if err := checkNotifier(config); err != nil {
+		return nil, err
+	}
+
+	source, err := readTemplate(config.Template, defaultNotificationTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := texttemplate.New("notification").Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s notifier template: %w", config.Type, err)
+	}
+
+	return &Notifier{config: config, template: template, client: &http.Client{Timeout: notifierTimeout}}, nil
+}
+
+// checkNotifier Make sure the notifier has a type we know and a URL we can post to.
+func checkNotifier(config NotifierConfiguration) error {
+	switch config.Type {
+	case NotifierSlack, NotifierDiscord, NotifierWebhook:
+	default:
+		return fmt.Errorf("unknown notifier type %q, expected slack, discord or webhook", config.Type)
+	}
+
+	if config.URL == "" {
+		return fmt.Errorf("%s notifier has no URL", config.Type)
+	}
+
+	webhookURL, err := url.Parse(config.URL)
+	if err != nil || (webhookURL.Scheme != "https" && webhookURL.Scheme != "http") || webhookURL.Host == "" {
+		return fmt.Errorf("%s notifier URL %q is not an http:// or https:// URL", config.Type, config.URL)
+	}
+
+	return nil
+}
+
+func NewRunNotification(runID string, changes []SyncChange, timestamp time.Time) RunNotification {
+	notification := RunNotification{RunID: runID, Timestamp: timestamp}
+
+	for _, change := range changes {
+		switch {
+		case change.Err != nil:
+			notification.Failed++
+		case change.Action == SyncCreate:
+			notification.Created++
+		case change.Action == SyncUpdate:
+			notification.Updated++
+		case change.Action == SyncDelete:
+			notification.Deleted++
+		}
+
+		notification.Entries = append(notification.Entries, NewAuditEntry(runID, change, timestamp))
+	}
+
+	return notification
+}
+
+// Notify Post the whole run as one message.
+func (notifier *Notifier) Notify(notification RunNotification) error {
+	var text bytes.Buffer
+	if err := notifier.template.Execute(&text, notification); err != nil {
+		return fmt.Errorf("unable to render %s notification: %w", notifier.config.Type, err)
+	}
+
+	notification.Text = text.String()
+
+	var payload any
+
+	switch notifier.config.Type {
+	case NotifierSlack:
+		payload = map[string]string{"text": truncate(notification.Text, slackMaxTextLength)}
+	case NotifierDiscord:
+		payload = map[string]string{"content": truncate(notification.Text, discordMaxContentLength)}
+	default:
+		payload = notification
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	if err = postWithRetry(notifier.client, notifier.config.URL, body); err != nil {
+		return fmt.Errorf("%s notification: %w", notifier.config.Type, err)
+	}
+
+	return nil
+}
+
+// postWithRetry POST the JSON body, retrying with backoff if the webhook is unavailable.
+func postWithRetry(client *http.Client, url string, body []byte) error {
+	delay := notifierInitialDelay
+
+	for attempt := 1; ; attempt++ {
+		retry, err := post(client, url, body)
+		if err == nil {
+			return nil
+		}
+
+		if !retry || attempt == notifierAttempts {
+			return fmt.Errorf("webhook failed after %d attempt(s): %w", attempt, err)
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// post Send the JSON body once.  The bool reports whether a failure is worth retrying.
+func post(client *http.Client, url string, body []byte) (bool, error) {
+	response, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusOK && response.StatusCode < http.StatusMultipleChoices {
+		return false, nil
+	}
+
+	retry := response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError
+
+	return retry, fmt.Errorf("webhook returned %s", response.Status)
+}
+
+func truncate(text string, maxLength int) string {
+	const ellipsis = "\n..."
+
+	if len(text) <= maxLength {
+		return text
+	}
+
+	return strings.ToValidUTF8(text[:maxLength-len(ellipsis)], "") + ellipsis
+}
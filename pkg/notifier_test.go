@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A big run makes a long message, which the chat services reject rather than cut short.
+func TestNotifyTruncatesLongMessages(t *testing.T) {
+	tests := []struct {
+		notifierType string
+		field        string
+		maxLength    int
+	}{
+		{NotifierSlack, "text", slackMaxTextLength},
+		{NotifierDiscord, "content", discordMaxContentLength},
+	}
+
+	for _, test := range tests {
+		t.Run(test.notifierType, func(t *testing.T) {
+			var payload map[string]string
+
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				if err := json.NewDecoder(request.Body).Decode(&payload); err != nil {
+					t.Errorf("decoding payload: %v", err)
+				}
+			}))
+			defer server.Close()
+
+			// A template that renders to more than the limit.
+			templatePath := filepath.Join(t.TempDir(), "notification.tmpl")
+			if err := os.WriteFile(templatePath, []byte(strings.Repeat("é", test.maxLength)), 0o600); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+
+			notifier, err := NewNotifier(NotifierConfiguration{
+				Type: test.notifierType, URL: server.URL, Template: templatePath,
+			})
+			if err != nil {
+				t.Fatalf("NewNotifier: %v", err)
+			}
+
+			if err = notifier.Notify(RunNotification{}); err != nil {
+				t.Fatalf("Notify: %v", err)
+			}
+
+			text := payload[test.field]
+			if len(text) > test.maxLength || !strings.HasSuffix(text, "\n...") {
+				t.Errorf("%s is %d bytes, want at most %d ending in ...", test.field, len(text), test.maxLength)
+			}
+
+			if !strings.HasPrefix(text, "é") || strings.Contains(text, "�") {
+				t.Errorf("%s was cut in the middle of a character", test.field)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package pkg
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// The ways a user can authorize us.  Google no longer supports pasting a code from the browser
+// (the out-of-band flow), and its device flow only allows a few scopes, none of which are ours, so the
+// browser is sent back to a local listener.  Over SSH, the listener's port can be forwarded.
+
+const (
+	AuthFlowLoopback = "loopback" // Google redirects the browser to a listener on 127.0.0.1
+	AuthFlowDevice   = "device"   // Recognized only to explain why it can't be used
+)
+
+// CheckAuthFlow Make sure the flow is one that can authorize us.
+func CheckAuthFlow(flow string) error {
+	switch flow {
+	case AuthFlowLoopback:
+		return nil
+	case AuthFlowDevice:
+		return fmt.Errorf("the %s flow can't be used, since Google's device authorization doesn't allow the "+
+			"Sheets, Calendar or Drive scopes; use %s, forwarding its port with ssh -L if the browser is on "+
+			"another computer", AuthFlowDevice, AuthFlowLoopback)
+	default:
+		return fmt.Errorf("unknown auth flow %q, expected %s", flow, AuthFlowLoopback)
+	}
+}
+
+// How long to wait for the user to finish in their browser.
+const loopbackTimeout = 10 * time.Minute
+
+const stateBytes = 32
+
+type authorizationResult struct {
+	code string
+	err  error
+}
+
+// loopbackFlowToken Authorize with a redirect to a temporary listener on 127.0.0.1, using PKCE so
+// an intercepted code is no use to anyone else.  A port of 0 picks any free port.
+func loopbackFlowToken(ctx context.Context, config *oauth2.Config, port int) (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(ctx, loopbackTimeout)
+	defer cancel()
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen for the authorization redirect: %w", err)
+	}
+	defer listener.Close()
+
+	state, err := newState()
+	if err != nil {
+		return nil, err
+	}
+
+	verifier := oauth2.GenerateVerifier()
+
+	loopbackConfig := *config
+	loopbackConfig.RedirectURL = fmt.Sprintf("http://%s/", listener.Addr())
+
+	results := make(chan authorizationResult, 1)
+	server := &http.Server{Handler: loopbackHandler(state, results), ReadHeaderTimeout: 10 * time.Second}
+
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	// Google adds the scopes granted before, so asking for more doesn't lose any.
+	authURL := loopbackConfig.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("include_granted_scopes", "true"))
+
+	// This is a prompt for the person running the program, not a log message.
+	fmt.Fprintf(os.Stderr, "Go to the following link in your browser to authorize go-brown-sports:\n%s\n\n"+
+		"If the browser is on another computer, forward the port first: ssh -L %d:127.0.0.1:%d ...\n",
+		authURL, listener.Addr().(*net.TCPAddr).Port, listener.Addr().(*net.TCPAddr).Port)
+
+	select {
+	case result := <-results:
+		if result.err != nil {
+			return nil, result.err
+		}
+
+		token, err := loopbackConfig.Exchange(ctx, result.code, oauth2.VerifierOption(verifier))
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve token from web: %w", err)
+		}
+
+		return token, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("gave up waiting for authorization: %w", ctx.Err())
+	}
+}
+
+// loopbackHandler Receive the redirect from Google.  Anything without our state is ignored, since
+// it didn't come from the authorization we started.
+func loopbackHandler(state string, results chan<- authorizationResult) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		query := request.URL.Query()
+
+		if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(state)) != 1 {
+			http.Error(writer, "Unexpected request.  Start again from the link in the terminal.", http.StatusBadRequest)
+
+			return
+		}
+
+		var result authorizationResult
+
+		switch {
+		case query.Get("error") != "":
+			result.err = fmt.Errorf("authorization was not granted: %s", query.Get("error"))
+		case query.Get("code") == "":
+			result.err = errors.New("the authorization redirect has no code")
+		default:
+			result.code = query.Get("code")
+		}
+
+		// Only the first redirect counts.
+		select {
+		case results <- result:
+		default:
+		}
+
+		if result.err != nil {
+			http.Error(writer, "Authorization failed.  See the terminal for details.", http.StatusBadRequest)
+
+			return
+		}
+
+		fmt.Fprintln(writer, "go-brown-sports is authorized.  You can close this window.")
+	})
+}
+
+// newState A random value tying the redirect to the authorization we started.
+func newState() (string, error) {
+	state := make([]byte, stateBytes)
+	if _, err := rand.Read(state); err != nil {
+		return "", fmt.Errorf("unable to generate state: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(state), nil
+}
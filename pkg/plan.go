@@ -0,0 +1,77 @@
+package pkg
+
+import (
+	"context"
+	mapset "github.com/deckarep/golang-set"
+	"go.opentelemetry.io/otel/attribute"
+	"sort"
+	"time"
+)
+
+// PlanChanges Work out what has to change on the calendar to match the spreadsheet: events missing
+// from the calendar are created, extra events are deleted, and events that differ are updated.
+// The changes are in that order, and sorted by key within each action.
+func PlanChanges(
+	ctx context.Context,
+	spreadsheetFutureEvents map[string]SportingEvent,
+	calendarFutureEvents map[string]SportingEvent,
+	calendarFutureEventIds map[string]string) []SyncChange {
+	_, span := StartSpan(ctx, "PlanChanges")
+	defer span.End()
+
+	// Create sets.  This makes determining what's missing and extra simple and straightforward.
+	calendarSet := mapset.NewSetFromSlice(GetKeysFromSportingEventMap(calendarFutureEvents))
+	spreadsheetSet := mapset.NewSetFromSlice(GetKeysFromSportingEventMap(spreadsheetFutureEvents))
+
+	var changes []SyncChange
+
+	for _, key := range sortedKeys(spreadsheetSet.Difference(calendarSet)) {
+		changes = append(changes, SyncChange{
+			Action: SyncCreate,
+			Key:    key,
+			After:  spreadsheetFutureEvents[key],
+		})
+	}
+
+	for _, key := range sortedKeys(calendarSet.Difference(spreadsheetSet)) {
+		changes = append(changes, SyncChange{
+			Action:          SyncDelete,
+			Key:             key,
+			CalendarEventID: calendarFutureEventIds[key],
+			Before:          calendarFutureEvents[key],
+		})
+	}
+
+	for _, key := range sortedKeys(calendarSet.Intersect(spreadsheetSet)) {
+		if calendarFutureEvents[key].IsMostlyEqual(spreadsheetFutureEvents[key]) {
+			continue
+		}
+
+		changes = append(changes, SyncChange{
+			Action:          SyncUpdate,
+			Key:             key,
+			CalendarEventID: calendarFutureEventIds[key],
+			Before:          calendarFutureEvents[key],
+			After:           spreadsheetFutureEvents[key],
+		})
+	}
+
+	status := NewRunStatus("", time.Time{}, time.Time{}, changes)
+	span.SetAttributes(
+		attribute.Int("missing", status.Missing),
+		attribute.Int("extra", status.Extra),
+		attribute.Int("updated", status.Updated))
+
+	return changes
+}
+
+func sortedKeys(set mapset.Set) []string {
+	keys := make([]string, 0, set.Cardinality())
+	for key := range set.Iter() {
+		keys = append(keys, key.(string))
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
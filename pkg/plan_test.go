@@ -0,0 +1,71 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// A venue or sport catalogue change moves events that are already on the calendar.
+func TestPlanChangesUpdatesLocation(t *testing.T) {
+	eastern, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+
+	event := SportingEvent{
+		Datetime: time.Date(2024, 2, 3, 19, 0, 0, 0, eastern),
+		Sport:    "Men's Ice Hockey",
+		Location: "Meehan Auditorium, 225 Hope St, Providence, RI 02912",
+		Roles:    []string{"Announcer: Pat Worker"},
+	}
+
+	// The way a sync sees the event it wrote: through the calendar cache.
+	calendarEvent := createCalendarEntryObject(event)
+	calendarEvent.Id = "event-id"
+	onCalendar := newCachedEvent(calendarEvent).sportingEvent()
+
+	if onCalendar.Location != event.Location {
+		t.Fatalf("cached location = %q, want %q", onCalendar.Location, event.Location)
+	}
+
+	calendarEvents := map[string]SportingEvent{event.GetKey(): onCalendar}
+	calendarEventIDs := map[string]string{event.GetKey(): "event-id"}
+
+	tests := []struct {
+		name       string
+		location   string
+		wantUpdate bool
+	}{
+		{"unchanged", event.Location, false},
+		{"moved", "Hockey East Arena, Lowell, MA", true},
+		{"removed", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spreadsheetEvent := event
+			spreadsheetEvent.Location = test.location
+
+			changes := PlanChanges(context.Background(), map[string]SportingEvent{event.GetKey(): spreadsheetEvent},
+				calendarEvents, calendarEventIDs)
+
+			if !test.wantUpdate {
+				if len(changes) != 0 {
+					t.Errorf("PlanChanges() = %+v, want no changes", changes)
+				}
+
+				return
+			}
+
+			if len(changes) != 1 || changes[0].Action != SyncUpdate || changes[0].CalendarEventID != "event-id" {
+				t.Fatalf("PlanChanges() = %+v, want an update of event-id", changes)
+			}
+
+			diff := DiffSportingEvents(changes[0].Before, changes[0].After)
+			if len(diff) != 1 || diff[0].Field != "location" || diff[0].After != test.location {
+				t.Errorf("DiffSportingEvents() = %+v, want just the location changed to %q", diff, test.location)
+			}
+		})
+	}
+}
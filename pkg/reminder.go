@@ -0,0 +1,237 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	htmltemplate "html/template"
+	"net/http"
+	texttemplate "text/template"
+	"time"
+)
+
+// Reminders go out to each worker shortly before their assignment and list the rest of the crew.
+// Sent reminders are recorded so that running the reminder mode repeatedly doesn't resend them.
+
+const (
+	ReminderEmail   = "email"
+	ReminderWebhook = "webhook"
+)
+
+const reminderSubject = "Reminder: {{.Role}} for {{.Sport}} on {{formatTime .Datetime}}"
+
+const defaultReminderTextTemplate = `Hi {{.Worker}},
+
+This is a reminder that you are scheduled as {{.Role}} for {{.Sport}} on {{formatTime .Datetime}}.
+{{- if .Location}}
+
+Location: {{.Location}}
+{{- end}}
+
+The crew for this event:
+{{- range .Crew}}
+  * {{.Role}}: {{.Worker}}
+{{- end}}
+`
+
+const defaultReminderHTMLTemplate = `<p>Hi {{.Worker}},</p>
+<p>This is a reminder that you are scheduled as <b>{{.Role}}</b> for {{.Sport}} on {{formatTime .Datetime}}.</p>
+{{- if .Location}}
+<p>Location: {{.Location}}</p>
+{{- end}}
+<p>The crew for this event:</p>
+<ul>
+{{- range .Crew}}
+  <li>{{.Role}}: {{.Worker}}</li>
+{{- end}}
+</ul>
+`
+
+var remindersSentBucket = []byte("sent")
+
+type Reminder struct {
+	EventKey string       `json:"eventKey"`
+	Worker   string       `json:"worker"`
+	Email    string       `json:"email"`
+	Role     string       `json:"role"`
+	Sport    string       `json:"sport"`
+	Datetime time.Time    `json:"datetime"`
+	Location string       `json:"location"`
+	Crew     []Assignment `json:"crew"`
+	Text     string       `json:"text"`
+}
+
+// BuildReminders Get one reminder per worker per event for the events starting within the window.
+// Workers without an email address are skipped.
+func BuildReminders(sportingEvents []SportingEvent, currentTime time.Time, window time.Duration) []Reminder {
+	var reminders []Reminder
+
+	windowEnd := currentTime.Add(window)
+
+	for _, sportingEvent := range sportingEvents {
+		if !sportingEvent.Datetime.After(currentTime) || sportingEvent.Datetime.After(windowEnd) {
+			continue
+		}
+
+		reminded := make(map[string]bool)
+
+		for _, assignment := range sportingEvent.Assignments {
+			// A worker with two roles at the same event gets one reminder.
+			if assignment.Email == "" || reminded[assignment.Email] {
+				continue
+			}
+
+			reminded[assignment.Email] = true
+
+			reminders = append(reminders, Reminder{
+				EventKey: sportingEvent.GetKey(),
+				Worker:   assignment.Worker,
+				Email:    assignment.Email,
+				Role:     assignment.Role,
+				Sport:    sportingEvent.Sport,
+				Datetime: sportingEvent.Datetime,
+				Location: sportingEvent.Location,
+				Crew:     sportingEvent.Assignments,
+			})
+		}
+	}
+
+	return reminders
+}
+
+type Reminders struct {
+	config       ReminderConfiguration
+	smtpConfig   SMTPConfiguration
+	db           *bolt.DB
+	client       *http.Client
+	subject      *texttemplate.Template
+	textTemplate *texttemplate.Template
+	htmlTemplate *htmltemplate.Template
+}
+
+// OpenReminders Prepare to send reminders.  Close must be called to release the store of sent reminders.
+func OpenReminders(config ReminderConfiguration, smtpConfig SMTPConfiguration) (*Reminders, error) {
+	switch config.Channel {
+	case ReminderEmail, ReminderWebhook:
+	default:
+		return nil, fmt.Errorf("unknown reminder channel %q, expected email or webhook", config.Channel)
+	}
+
+	if config.Channel == ReminderWebhook && config.WebhookURL == "" {
+		return nil, fmt.Errorf("reminder webhook has no URL")
+	}
+
+	funcs := map[string]any{"formatTime": formatFriendlyTime}
+	subject := texttemplate.Must(texttemplate.New("subject").Funcs(funcs).Parse(reminderSubject))
+
+	textSource, err := readTemplate(config.TextTemplate, defaultReminderTextTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	textTemplate, err := texttemplate.New("reminder.txt").Funcs(funcs).Parse(textSource)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reminder text template: %w", err)
+	}
+
+	htmlSource, err := readTemplate(config.HTMLTemplate, defaultReminderHTMLTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlTemplate, err := htmltemplate.New("reminder.html").Funcs(funcs).Parse(htmlSource)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reminder HTML template: %w", err)
+	}
+
+	db, err := bolt.Open(config.StorePath, 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open reminder store %s: %w", config.StorePath, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(remindersSentBucket)
+
+		return err
+	})
+	if err != nil {
+		db.Close()
+
+		return nil, fmt.Errorf("unable to initialize reminder store %s: %w", config.StorePath, err)
+	}
+
+	return &Reminders{
+		config:       config,
+		smtpConfig:   smtpConfig,
+		db:           db,
+		client:       &http.Client{Timeout: notifierTimeout},
+		subject:      subject,
+		textTemplate: textTemplate,
+		htmlTemplate: htmlTemplate,
+	}, nil
+}
+
+func (reminders *Reminders) Close() error {
+	return reminders.db.Close()
+}
+
+// WasSent Check whether this reminder has already gone out.  The key includes the event time,
+// so a worker whose game moves gets a fresh reminder.
+func (reminders *Reminders) WasSent(reminder Reminder) bool {
+	sent := false
+
+	_ = reminders.db.View(func(tx *bolt.Tx) error {
+		sent = tx.Bucket(remindersSentBucket).Get(reminderKey(reminder)) != nil
+
+		return nil
+	})
+
+	return sent
+}
+
+// Send Deliver the reminder and record that it was sent.
+func (reminders *Reminders) Send(reminder Reminder) error {
+	var subject, textBody, htmlBody bytes.Buffer
+
+	if err := reminders.subject.Execute(&subject, reminder); err != nil {
+		return fmt.Errorf("unable to render reminder for %s: %w", reminder.Email, err)
+	}
+
+	if err := reminders.textTemplate.Execute(&textBody, reminder); err != nil {
+		return fmt.Errorf("unable to render reminder for %s: %w", reminder.Email, err)
+	}
+
+	var err error
+
+	if reminders.config.Channel == ReminderEmail {
+		if err = reminders.htmlTemplate.Execute(&htmlBody, reminder); err != nil {
+			return fmt.Errorf("unable to render reminder for %s: %w", reminder.Email, err)
+		}
+
+		err = SendEmail(reminders.smtpConfig, reminder.Email, subject.String(), textBody.String(), htmlBody.String())
+	} else {
+		reminder.Text = textBody.String()
+
+		var body []byte
+
+		body, err = json.Marshal(reminder)
+		if err != nil {
+			return err
+		}
+
+		err = postWithRetry(reminders.client, reminders.config.WebhookURL, body)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return reminders.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(remindersSentBucket).Put(reminderKey(reminder), []byte(time.Now().Format(time.RFC3339)))
+	})
+}
+
+func reminderKey(reminder Reminder) []byte {
+	return []byte(fmt.Sprintf("%s | %s", reminder.EventKey, reminder.Email))
+}
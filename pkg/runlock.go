@@ -0,0 +1,43 @@
+//go:build unix
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// ErrSyncInProgress Another run (perhaps from cron, perhaps from the daemon) holds the run lock.
+var ErrSyncInProgress = errors.New("another sync is already in progress")
+
+// RunLock An exclusive lock on a file, so only one process at a time can change the calendar.
+// The operating system releases the lock if the process dies, so there are no stale locks to clean up.
+type RunLock struct {
+	file *os.File
+}
+
+func AcquireRunLock(path string) (*RunLock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open lock file %s: %w", path, err)
+	}
+
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		file.Close()
+
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrSyncInProgress
+		}
+
+		return nil, fmt.Errorf("unable to lock %s: %w", path, err)
+	}
+
+	return &RunLock{file: file}, nil
+}
+
+func (runLock *RunLock) Release() error {
+	return runLock.file.Close()
+}
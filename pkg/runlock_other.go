@@ -0,0 +1,37 @@
+//go:build !unix
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrSyncInProgress Another run (perhaps from cron, perhaps from the daemon) holds the run lock.
+var ErrSyncInProgress = errors.New("another sync is already in progress")
+
+// RunLock Without flock, fall back to a file that exists only while a run is in progress.
+// If a run crashes, the lock file has to be removed by hand.
+type RunLock struct {
+	path string
+}
+
+func AcquireRunLock(path string) (*RunLock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, ErrSyncInProgress
+		}
+
+		return nil, fmt.Errorf("unable to create lock file %s: %w", path, err)
+	}
+
+	file.Close()
+
+	return &RunLock{path: path}, nil
+}
+
+func (runLock *RunLock) Release() error {
+	return os.Remove(runLock.path)
+}
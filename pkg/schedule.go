@@ -0,0 +1,184 @@
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule A standard five field cron expression: minute, hour, day of month, month, day of week.
+// Each field accepts *, single values, ranges (1-5), lists (1,3,5) and steps (*/15 or 8-18/2).
+// As in cron, when both day of month and day of week are restricted, a time matching either one matches.
+type CronSchedule struct {
+	minutes       map[int]bool
+	hours         map[int]bool
+	daysOfMonth   map[int]bool
+	months        map[int]bool
+	daysOfWeek    map[int]bool
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+func ParseCronSchedule(expression string) (*CronSchedule, error) {
+	const fieldCount = 5
+
+	fields := strings.Fields(expression)
+	if len(fields) != fieldCount {
+		return nil, fmt.Errorf("cron expression %q must have %d fields", expression, fieldCount)
+	}
+
+	limits := []struct {
+		name     string
+		min, max int
+	}{
+		{"minute", 0, 59},
+		{"hour", 0, 23},
+		{"day of month", 1, 31},
+		{"month", 1, 12},
+		{"day of week", 0, 7}, // Both 0 and 7 are Sunday
+	}
+
+	parsed := make([]map[int]bool, fieldCount)
+
+	for index, field := range fields {
+		values, err := parseCronField(field, limits[index].min, limits[index].max)
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q has an invalid %s: %w", expression, limits[index].name, err)
+		}
+
+		parsed[index] = values
+	}
+
+	if parsed[4][7] {
+		parsed[4][0] = true
+	}
+
+	return &CronSchedule{
+		minutes:       parsed[0],
+		hours:         parsed[1],
+		daysOfMonth:   parsed[2],
+		months:        parsed[3],
+		daysOfWeek:    parsed[4],
+		anyDayOfMonth: fields[2] == "*",
+		anyDayOfWeek:  fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, min int, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		low, high := min, max
+
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+
+			var err error
+
+			low, err = strconv.Atoi(lowPart)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", lowPart)
+			}
+
+			high = low
+			if isRange {
+				high, err = strconv.Atoi(highPart)
+				if err != nil {
+					return nil, fmt.Errorf("invalid value %q", highPart)
+				}
+			} else if hasStep {
+				high = max // "5/15" means starting at 5, every 15
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return nil, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+
+		for value := low; value <= high; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, nil
+}
+
+// Next Get the first time after the given time that matches the schedule.
+func (schedule *CronSchedule) Next(after time.Time) time.Time {
+	// Cron schedules can't match anything more than a few years out, so give up eventually
+	// rather than looping forever on something like February 30th.
+	const maxYears = 5
+
+	next := after.Truncate(time.Minute).Add(time.Minute)
+	limit := next.AddDate(maxYears, 0, 0)
+
+	for next.Before(limit) {
+		if !schedule.months[int(next.Month())] {
+			next = advance(next, time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location()))
+
+			continue
+		}
+
+		if !schedule.matchesDay(next) {
+			next = advance(next, time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location()))
+
+			continue
+		}
+
+		if !schedule.hours[next.Hour()] {
+			next = advance(next, time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location()))
+
+			continue
+		}
+
+		if !schedule.minutes[next.Minute()] {
+			next = next.Add(time.Minute)
+
+			continue
+		}
+
+		return next
+	}
+
+	return time.Time{}
+}
+
+// advance time.Date picks the earlier time when asked for a time that doesn't exist because of
+// a daylight saving time change (e.g. 2:00am becomes 1:00am EST).  Step to the next hour instead,
+// so we never go backwards.
+func advance(current time.Time, candidate time.Time) time.Time {
+	if candidate.After(current) {
+		return candidate
+	}
+
+	return current.Truncate(time.Hour).Add(time.Hour)
+}
+
+func (schedule *CronSchedule) matchesDay(datetime time.Time) bool {
+	dayOfMonth := schedule.daysOfMonth[datetime.Day()]
+	dayOfWeek := schedule.daysOfWeek[int(datetime.Weekday())]
+
+	switch {
+	case schedule.anyDayOfMonth && schedule.anyDayOfWeek:
+		return true
+	case schedule.anyDayOfMonth:
+		return dayOfWeek
+	case schedule.anyDayOfWeek:
+		return dayOfMonth
+	default:
+		return dayOfMonth || dayOfWeek
+	}
+}
@@ -0,0 +1,63 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+	// A Wednesday.
+	after := time.Date(2024, 1, 3, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		expression string
+		wantNext   time.Time // Zero if nothing ever matches
+		wantErr    bool
+	}{
+		{"* * * * *", time.Date(2024, 1, 3, 10, 18, 0, 0, time.UTC), false},
+		{"*/15 * * * *", time.Date(2024, 1, 3, 10, 30, 0, 0, time.UTC), false},
+		{"5/15 * * * *", time.Date(2024, 1, 3, 10, 20, 0, 0, time.UTC), false},
+		{"0 6 * * *", time.Date(2024, 1, 4, 6, 0, 0, 0, time.UTC), false},
+		{"0 6,18 * * *", time.Date(2024, 1, 3, 18, 0, 0, 0, time.UTC), false},
+		{"30 9-17/2 * * *", time.Date(2024, 1, 3, 11, 30, 0, 0, time.UTC), false},
+		{"0 0 1 * *", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), false},
+		{"0 0 * * 0", time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC), false},
+		{"0 0 * * 7", time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC), false},   // 7 is Sunday too
+		{"0 0 15 * 5", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), false},  // The 15th or a Friday
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), false}, // Leap day
+		{"0 0 30 2 *", time.Time{}, false},                                  // Valid, but never happens
+		{"* * * *", time.Time{}, true},                                      // Too few fields
+		{"* * * * * *", time.Time{}, true},                                  // Too many fields
+		{"60 * * * *", time.Time{}, true},
+		{"* 24 * * *", time.Time{}, true},
+		{"* * 0 * *", time.Time{}, true},
+		{"* * * 13 *", time.Time{}, true},
+		{"* * * * 8", time.Time{}, true},
+		{"10-5 * * * *", time.Time{}, true},
+		{"*/0 * * * *", time.Time{}, true},
+		{"a * * * *", time.Time{}, true},
+		{"1-b * * * *", time.Time{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			schedule, err := ParseCronSchedule(test.expression)
+
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("ParseCronSchedule(%q) succeeded, want an error", test.expression)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseCronSchedule(%q): %v", test.expression, err)
+			}
+
+			if next := schedule.Next(after); !next.Equal(test.wantNext) {
+				t.Errorf("Next(%s) = %s, want %s", after, next, test.wantNext)
+			}
+		})
+	}
+}
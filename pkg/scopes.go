@@ -0,0 +1,249 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Each feature only asks for the access it needs.  A profile is the set of scopes for one feature.
+// There's one saved token with everything that's been granted, but each command's access tokens are
+// narrowed to its profiles' scopes when they're refreshed, so validating the spreadsheet can't change
+// the calendar, even after a sync has been authorized.
+
+const (
+	ScopeSpreadsheetsReadOnly  = "https://www.googleapis.com/auth/spreadsheets.readonly"
+	ScopeSpreadsheets          = "https://www.googleapis.com/auth/spreadsheets"
+	ScopeCalendarEvents        = "https://www.googleapis.com/auth/calendar.events"
+	ScopeDriveMetadataReadOnly = "https://www.googleapis.com/auth/drive.metadata.readonly"
+)
+
+const (
+	ProfileReadOnly  = "readonly"  // Reading the spreadsheet: validate, export, workers and remind
+	ProfileCalendar  = "calendar"  // Syncing: writing calendar events, and checking the spreadsheet revision
+	ProfileWriteback = "writeback" // Writing the sync status into the spreadsheet
+	ProfileWatch     = "watch"     // Watching the spreadsheet with Drive push notifications
+)
+
+// Profiles The scopes each profile needs.
+var Profiles = map[string][]string{
+	ProfileReadOnly:  {ScopeSpreadsheetsReadOnly},
+	ProfileCalendar:  {ScopeSpreadsheetsReadOnly, ScopeCalendarEvents, ScopeDriveMetadataReadOnly},
+	ProfileWriteback: {ScopeSpreadsheets},
+	ProfileWatch:     {ScopeDriveMetadataReadOnly},
+}
+
+// impliedScopes Scopes that include other scopes.
+var impliedScopes = map[string][]string{
+	ScopeSpreadsheets: {ScopeSpreadsheetsReadOnly},
+}
+
+const tokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+
+// SyncProfiles The profiles a sync needs, given what's turned on in the config.
+func SyncProfiles(cfg *Config) []string {
+	profiles := []string{ProfileCalendar}
+
+	if cfg.GetWritebackConfiguration().Enabled {
+		profiles = append(profiles, ProfileWriteback)
+	}
+
+	return profiles
+}
+
+// ConfiguredProfiles The profiles for everything that's turned on in the config, so one
+// authorization covers sync and serve.
+func ConfiguredProfiles(cfg *Config) []string {
+	profiles := SyncProfiles(cfg)
+
+	if cfg.GetWatchConfiguration().Enabled {
+		profiles = append(profiles, ProfileWatch)
+	}
+
+	return profiles
+}
+
+// ScopesFor The scopes the profiles need, leaving out any that another one includes.
+func ScopesFor(profiles ...string) ([]string, error) {
+	var scopes []string
+
+	for _, profile := range profiles {
+		profileScopes, ok := Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown auth profile %q", profile)
+		}
+
+		for _, scope := range profileScopes {
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	var needed []string
+
+	for _, scope := range scopes {
+		if !isImplied(scope, scopes) {
+			needed = append(needed, scope)
+		}
+	}
+
+	return needed, nil
+}
+
+// MissingScopes The needed scopes that weren't granted.
+func MissingScopes(granted []string, needed []string) []string {
+	var missing []string
+
+	for _, scope := range needed {
+		if !slices.Contains(granted, scope) && !isImplied(scope, granted) {
+			missing = append(missing, scope)
+		}
+	}
+
+	return missing
+}
+
+func isImplied(scope string, scopes []string) bool {
+	for _, other := range scopes {
+		if slices.Contains(impliedScopes[other], scope) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TokenScopes The scopes Google said it granted with the token, or nil if we don't know.
+func TokenScopes(token *oauth2.Token) []string {
+	scope, _ := token.Extra("scope").(string)
+
+	return strings.Fields(scope)
+}
+
+// withScopes Record the granted scopes on the token.
+func withScopes(token *oauth2.Token, scopes []string) *oauth2.Token {
+	return token.WithExtra(map[string]any{"scope": strings.Join(scopes, " ")})
+}
+
+// extraScopes The granted scopes that weren't asked for, and aren't included in one that was.
+func extraScopes(granted []string, asked []string) []string {
+	var extra []string
+
+	for _, scope := range granted {
+		if !slices.Contains(asked, scope) && !isImplied(scope, asked) {
+			extra = append(extra, scope)
+		}
+	}
+
+	return extra
+}
+
+// refreshScoped Get an access token for just config's scopes from the refresh token, which Google
+// allows as long as they were all granted.  With no scopes, the token has everything granted.  The
+// oauth2 package can't ask for a scope when refreshing, so this makes the request itself.
+func refreshScoped(ctx context.Context, config *oauth2.Config, refreshToken string) (*oauth2.Token, error) {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {config.ClientID},
+		"client_secret": {config.ClientSecret},
+	}
+
+	if len(config.Scopes) > 0 {
+		form.Set("scope", strings.Join(config.Scopes, " "))
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, config.Endpoint.TokenURL,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("unable to refresh the token: %w", err)
+	}
+	defer response.Body.Close()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int64  `json:"expires_in"`
+		RefreshToken     string `json:"refresh_token"`
+		Scope            string `json:"scope"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	if err = json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("unable to decode the refreshed token: %w", err)
+	}
+
+	switch {
+	case body.Error == "invalid_grant":
+		return nil, fmt.Errorf("%w (%s)", ErrTokenRevoked, body.ErrorDescription)
+	case response.StatusCode != http.StatusOK || body.AccessToken == "":
+		return nil, fmt.Errorf("unable to refresh the token: %s %s %s", response.Status, body.Error,
+			body.ErrorDescription)
+	}
+
+	// An access token with more than was asked for would defeat the point, so don't use one.
+	granted := strings.Fields(body.Scope)
+	if len(config.Scopes) > 0 {
+		if extra := extraScopes(granted, config.Scopes); len(extra) > 0 || len(granted) == 0 {
+			return nil, fmt.Errorf("google didn't narrow the access token to %s (it has %q)",
+				strings.Join(config.Scopes, " "), body.Scope)
+		}
+	}
+
+	if body.RefreshToken == "" {
+		body.RefreshToken = refreshToken
+	}
+
+	token := &oauth2.Token{
+		AccessToken:  body.AccessToken,
+		TokenType:    body.TokenType,
+		RefreshToken: body.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(body.ExpiresIn) * time.Second),
+	}
+
+	return withScopes(token, granted), nil
+}
+
+// FetchGrantedScopes Ask Google which scopes an access token has.
+func FetchGrantedScopes(ctx context.Context, token *oauth2.Token) ([]string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		tokenInfoURL+"?"+url.Values{"access_token": {token.AccessToken}}.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("unable to look up token scopes: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to look up token scopes: %s", response.Status)
+	}
+
+	var tokenInfo struct {
+		Scope string `json:"scope"`
+	}
+
+	if err = json.NewDecoder(response.Body).Decode(&tokenInfo); err != nil {
+		return nil, fmt.Errorf("unable to decode token scopes: %w", err)
+	}
+
+	return strings.Fields(tokenInfo.Scope), nil
+}
@@ -0,0 +1,158 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"golang.org/x/oauth2"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// fakeTokenEndpoint Answers refresh requests with the response, recording the scope asked for.
+func fakeTokenEndpoint(t *testing.T, status int, response map[string]any, asked *string) *oauth2.Config {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if err := request.ParseForm(); err != nil {
+			t.Errorf("ParseForm: %v", err)
+		}
+
+		if request.PostForm.Get("grant_type") != "refresh_token" {
+			t.Errorf("grant_type = %q, want refresh_token", request.PostForm.Get("grant_type"))
+		}
+
+		*asked = request.PostForm.Get("scope")
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(status)
+		_ = json.NewEncoder(writer).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	return &oauth2.Config{ClientID: "client", Endpoint: oauth2.Endpoint{TokenURL: server.URL}}
+}
+
+func TestPersistingTokenSourceNarrowsScopes(t *testing.T) {
+	saved := withScopes(&oauth2.Token{AccessToken: "everything", RefreshToken: "refresh"},
+		[]string{ScopeSpreadsheets, ScopeCalendarEvents, ScopeDriveMetadataReadOnly})
+
+	tests := []struct {
+		name        string
+		status      int
+		response    map[string]any
+		wantErr     bool
+		wantRevoked bool
+		wantRefresh string // The refresh token saved afterwards, or "" if nothing is saved
+	}{
+		{
+			name:   "narrowed",
+			status: http.StatusOK,
+			response: map[string]any{"access_token": "readonly", "expires_in": 3600,
+				"scope": ScopeSpreadsheetsReadOnly},
+			wantRefresh: "refresh",
+		},
+		{
+			name:   "new refresh token",
+			status: http.StatusOK,
+			response: map[string]any{"access_token": "readonly", "expires_in": 3600, "scope": ScopeSpreadsheetsReadOnly,
+				"refresh_token": "rotated"},
+			wantRefresh: "rotated",
+		},
+		{
+			name:   "not narrowed",
+			status: http.StatusOK,
+			response: map[string]any{"access_token": "all", "expires_in": 3600,
+				"scope": ScopeSpreadsheets + " " + ScopeCalendarEvents},
+			wantErr: true,
+		},
+		{
+			name:     "scope not said",
+			status:   http.StatusOK,
+			response: map[string]any{"access_token": "unknown", "expires_in": 3600},
+			wantErr:  true,
+		},
+		{
+			name:        "revoked",
+			status:      http.StatusBadRequest,
+			response:    map[string]any{"error": "invalid_grant", "error_description": "Token has been expired or revoked."},
+			wantErr:     true,
+			wantRevoked: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var asked string
+
+			config := fakeTokenEndpoint(t, test.status, test.response, &asked)
+			config.Scopes = []string{ScopeSpreadsheetsReadOnly}
+
+			store, err := NewTokenStore(filepath.Join(t.TempDir(), "token.json"), AuthConfiguration{})
+			if err != nil {
+				t.Fatalf("NewTokenStore: %v", err)
+			}
+
+			token, err := NewPersistingTokenSource(context.Background(), config, store, saved, slog.Default()).Token()
+
+			if asked != ScopeSpreadsheetsReadOnly {
+				t.Errorf("asked for scope %q, want %s", asked, ScopeSpreadsheetsReadOnly)
+			}
+
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("Token() = %v, want an error", token.AccessToken)
+				}
+
+				if errors.Is(err, ErrTokenRevoked) != test.wantRevoked {
+					t.Errorf("Token() error = %v, revoked = %v", err, test.wantRevoked)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Token(): %v", err)
+			}
+
+			if token.AccessToken == saved.AccessToken {
+				t.Error("Token() returned the saved access token, which has every scope")
+			}
+
+			stored, err := store.Load()
+			switch {
+			case test.wantRefresh == "" && err == nil:
+				t.Errorf("saved %+v, want nothing saved", stored)
+			case test.wantRefresh != "" && err != nil:
+				t.Errorf("Load: %v", err)
+			case test.wantRefresh != "" && stored.RefreshToken != test.wantRefresh:
+				t.Errorf("saved refresh token %q, want %q", stored.RefreshToken, test.wantRefresh)
+			case test.wantRefresh != "" &&
+				(stored.AccessToken != token.AccessToken || !stored.Expiry.Equal(token.Expiry)):
+				t.Errorf("saved access token %q expiring %s, want the refreshed one", stored.AccessToken, stored.Expiry)
+			case test.wantRefresh != "" && len(TokenScopes(stored)) != 3:
+				t.Errorf("saved scopes %v, want the granted scopes kept", TokenScopes(stored))
+			}
+		})
+	}
+}
+
+func TestMissingAndExtraScopes(t *testing.T) {
+	granted := []string{ScopeSpreadsheets, ScopeCalendarEvents}
+
+	missing := MissingScopes(granted, []string{ScopeSpreadsheetsReadOnly, ScopeDriveMetadataReadOnly})
+	if len(missing) != 1 || missing[0] != ScopeDriveMetadataReadOnly {
+		t.Errorf("MissingScopes = %v, want just %s", missing, ScopeDriveMetadataReadOnly)
+	}
+
+	if extra := extraScopes([]string{ScopeSpreadsheetsReadOnly}, []string{ScopeSpreadsheets}); len(extra) != 0 {
+		t.Errorf("extraScopes = %v, want none, since spreadsheets includes spreadsheets.readonly", extra)
+	}
+
+	if extra := extraScopes([]string{ScopeSpreadsheets}, []string{ScopeSpreadsheetsReadOnly}); len(extra) != 1 {
+		t.Errorf("extraScopes = %v, want spreadsheets, which is more than spreadsheets.readonly", extra)
+	}
+}
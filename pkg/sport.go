@@ -0,0 +1,56 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The sport catalogue lists an organization's sports and where their home events are.  Without one,
+// the Brown sports are used, and any sport on the spreadsheet is fine.  With one, sports that aren't
+// in it are reported, since they're usually typos that would otherwise go on the calendar as is.
+
+// Sport An entry in the sport catalogue.
+type Sport struct {
+	Name     string `yaml:"name"`
+	Location string `yaml:"location"` // Where home events are, for the calendar.  Empty if it varies.
+}
+
+// GetSportLocation Where events for the sport are, or "" if the catalogue doesn't say.
+func (cfg *Config) GetSportLocation(sport string) string {
+	for _, entry := range cfg.GetSports() {
+		if entry.Name == sport {
+			return entry.Location
+		}
+	}
+
+	return ""
+}
+
+// sportDiagnostic Check the sport against a configured catalogue.  Returns false if it's fine.
+func (cfg *Config) sportDiagnostic(sport string, month string, rowNumber int, column int) (Diagnostic, bool) {
+	if len(cfg.Sports) == 0 {
+		return Diagnostic{}, false
+	}
+
+	fix := fmt.Sprintf("Use one of the sports in the config, or add %s to it", sport)
+
+	for _, entry := range cfg.Sports {
+		if entry.Name == sport {
+			return Diagnostic{}, false
+		}
+
+		if strings.EqualFold(strings.Join(strings.Fields(sport), " "), entry.Name) {
+			fix = fmt.Sprintf("Change the sport to %s, as it is in the config", entry.Name)
+		}
+	}
+
+	return Diagnostic{
+		Rule:     RuleUnknownSport,
+		Severity: SeverityWarning,
+		Tab:      month,
+		Row:      rowNumber,
+		Column:   column,
+		Message:  fmt.Sprintf("%s isn't in the sport catalogue", sport),
+		Fix:      fix,
+	}, true
+}
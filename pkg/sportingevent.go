@@ -7,10 +7,23 @@ import (
 )
 
 type SportingEvent struct {
-	Datetime time.Time
-	Sport    string
-	Emails   []string
-	Roles    []string // Text representation
+	Datetime    time.Time
+	Sport       string
+	Location    string // From the venue or the sport catalogue
+	Emails      []string
+	Roles       []string // Text representation
+	Assignments []Assignment
+	Spreadsheet string // The name of the spreadsheet source.  Not set for events read from the calendar.
+	Tab         string // Where the event is on the spreadsheet.  Not set for events read from the calendar.
+	Row         int
+}
+
+// Assignment A single worker in a single role.  Unlike Roles, this includes workers who are not
+// in the Worker Contact Info tab (their Email is blank).
+type Assignment struct {
+	Role   string
+	Worker string
+	Email  string
 }
 
 func (event SportingEvent) Format() string {
@@ -32,6 +45,7 @@ func (event SportingEvent) IsMostlyEqual(event2 SportingEvent) bool {
 	// TODO using reflect() is _probably_ unnecessary here.  Need to test using == instead.
 	return event.Datetime.Equal(event2.Datetime) &&
 		event.Sport == event2.Sport &&
+		event.Location == event2.Location &&
 		reflect.DeepEqual(event.Roles, event2.Roles)
 }
 
@@ -3,120 +3,361 @@ package pkg
 import (
 	"context"
 	"fmt"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
-	"log"
 	"net/http"
 	"strings"
 	"time"
 )
 
-// Specify the column number of our required columns.
-// If any changes are made to these, the requiredHeaders variable (see below) should also be updated.
+// Where the date, time and sport columns are assumed to be if their headers can't be found.
 const dateColumnNumber = 0
 const timeColumnNumber = 1
 const sportColumnNumber = 2
 
+// WorkerContactInfoTab The tab listing every worker and their email address.
+const WorkerContactInfoTab = "Worker Contact Info"
+
+// monthTabs The sheet IDs (tabs) in the spreadsheet with the events, one for each month.
+var monthTabs = []string{
+	"September",
+	"October",
+	"November",
+	"December",
+	"January",
+	"February",
+	"March",
+	"April",
+	"May",
+}
+
+// skippedMonthTabs Tabs the sync leaves out until they're fixed, and why.  The validator still checks them.
+var skippedMonthTabs = map[string]string{
+	// TODO Need to ask Kelvin to fix the spreadsheet -- November does not have "Date" in cell A1.
+	"November": `the tab does not have "Date" in cell A1`,
+}
+
+// The date and time formats on the spreadsheet, once resolveDatetime has tidied up the time.
+const (
+	spreadsheetDateLayout = "Monday, January 2, 2006"
+	spreadsheetTimeLayout = "3:04pm"
+)
+
+// Worker An entry in the Worker Contact Info tab.
+type Worker struct {
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	Spreadsheet string `json:"spreadsheet,omitempty"`
+	Row         int    `json:"row"`
+}
+
+// eventColumn One of the date, time, sport and venue columns in a tab.
+type eventColumn struct {
+	header string
+	index  int
+}
+
+// eventColumns Where the date, time, sport and venue are in a tab.  Every other column is a role.  The
+// venue's index is -1 if the tab doesn't have one.
+type eventColumns struct {
+	date  eventColumn
+	time  eventColumn
+	sport eventColumn
+	venue eventColumn
+}
+
 func AccessSpreadsheet(ctx context.Context, client *http.Client) (*sheets.Service, error) {
 	srv, err := sheets.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
-		log.Fatalf("Unable to retrieve Sheets client: %v", err)
+		return nil, fmt.Errorf("unable to retrieve Sheets client: %w", err)
 	}
 
-	return srv, err
+	return srv, nil
 }
 
-// GetSpreadsheetMap Get a map with key: datetime+sport and value: SportingEvent struct of all the events
-// on all months on the spreadsheet.
-func GetSpreadsheetMap(sheetService *sheets.Service, currentTime time.Time) map[string]SportingEvent {
-	nameToEmailMap := loadNameToEmailMap(sheetService, GetSpreadsheetID())
-
-	// This list corresponds to the sheet IDs (tabs) in the spreadsheet.
-	monthList := []string{
-		"September",
-		"October",
-		// TODO Need to ask Kelvin to fix the spreadsheet -- November does not have "Date" in cell A1.
-		// "November",
-		"December",
-		"January",
-		"February",
-		"March",
-		"April",
-		"May",
+// LoadWorkerDirectory Get a map of worker name to email address from the Worker Contact Info tabs.
+func LoadWorkerDirectory(
+	ctx context.Context,
+	cfg *Config,
+	sheetService *sheets.Service,
+	diagnostics *Diagnostics) (map[string]string, error) {
+	workers, err := LoadWorkers(ctx, cfg, sheetService, diagnostics)
+	if err != nil {
+		return nil, err
 	}
 
-	spreadsheetFutureEvents := make(map[string]SportingEvent)
+	return BuildWorkerDirectory(workers, diagnostics), nil
+}
+
+// GetSpreadsheetEvents Get all the events, past and future, on the tabs of every spreadsheet.  An event
+// on more than one row is only included once.  Any problems with the spreadsheets are added to diagnostics.
+func GetSpreadsheetEvents(
+	ctx context.Context,
+	cfg *Config,
+	sheetService *sheets.Service,
+	nameToEmailMap map[string]string,
+	diagnostics *Diagnostics) (_ []SportingEvent, err error) {
+	ctx, span := StartSpan(ctx, "GetSpreadsheetEvents")
+	defer func() { EndSpan(span, err) }()
 
-	for _, month := range monthList {
-		monthEvents := LoadMonthAssignments(sheetService, GetSpreadsheetID(), month, nameToEmailMap)
-		for _, event := range monthEvents {
-			if event.Datetime.After(currentTime) {
-				spreadsheetFutureEvents[event.GetKey()] = event
+	var sportingEvents []SportingEvent
+
+	for _, source := range cfg.GetSpreadsheetSources() {
+		diagnostics.SetSpreadsheet(source.Name)
+
+		for _, tab := range source.Tabs {
+			tabEvents, err := LoadMonthAssignments(ctx, cfg, source, sheetService, tab, nameToEmailMap, diagnostics)
+			if err != nil {
+				return nil, err
 			}
+
+			sportingEvents = append(sportingEvents, tabEvents...)
+		}
+	}
+
+	diagnostics.SetSpreadsheet("")
+	sportingEvents = MergeEvents(sportingEvents, diagnostics)
+
+	span.SetAttributes(attribute.Int("events", len(sportingEvents)))
+
+	return sportingEvents, nil
+}
+
+// FilterFutureEvents Get a map with key: datetime+sport and value: SportingEvent struct of the events
+// that start after currentTime.
+func FilterFutureEvents(sportingEvents []SportingEvent, currentTime time.Time) map[string]SportingEvent {
+	futureEvents := make(map[string]SportingEvent)
+
+	for _, event := range sportingEvents {
+		if event.Datetime.After(currentTime) {
+			futureEvents[event.GetKey()] = event
 		}
 	}
 
-	return spreadsheetFutureEvents
+	return futureEvents
 }
 
+// LoadMonthAssignments Get the events on one tab of a spreadsheet.  The tabs are usually months, but
+// a spreadsheet can list any tabs with events on them.
 func LoadMonthAssignments(
+	ctx context.Context,
+	cfg *Config,
+	source SpreadsheetSource,
 	srv *sheets.Service,
-	spreadsheetID string,
 	month string,
-	nameToEmailMap map[string]string) []SportingEvent {
-	readRange := fmt.Sprintf("%s!A:ZZ", month)
+	nameToEmailMap map[string]string,
+	diagnostics *Diagnostics) (_ []SportingEvent, err error) {
+	ctx, span := StartSpan(ctx, "LoadMonthAssignments",
+		attribute.String("spreadsheet", source.Name), attribute.String("tab", month))
+	defer func() { EndSpan(span, err) }()
 
-	rows := loadSpreadsheetRows(srv, spreadsheetID, readRange)
+	readRange := fmt.Sprintf("%s!A:ZZ", quoteTabName(month))
+
+	rows, err := loadSpreadsheetRows(ctx, srv, source.SpreadsheetID, readRange)
+	if err != nil {
+		return nil, err
+	}
+
+	// An empty tab has no headers and no events.
+	if len(rows) == 0 {
+		diagnostics.Add(Diagnostic{
+			Rule:     RuleEmptyTab,
+			Severity: SeverityWarning,
+			Tab:      month,
+			Message:  "The tab is empty",
+			Fix: fmt.Sprintf("Add the %s, %s and %s headers in row 1, or leave the tab out of the sync",
+				source.Columns.Date, source.Columns.Time, source.Columns.Sport),
+		})
+
+		return nil, nil
+	}
 
 	headers := rows[0]
 	eventData := rows[1:]
 
-	// The first columns are required.  If they don't exist in the correct order... panic!
-	// The remaining columns are all treated as named Roles, and we don't care if more columns are added.
-	requiredHeaders := []string{"Date", "Time", "Sport"}
-	for index, expectedHeader := range requiredHeaders {
-		if headers[index] != expectedHeader {
-			log.Printf("Unexpected headers in month %s.  Expected %s, found %s.", month, expectedHeader, headers[0])
-		}
-	}
+	// The date, time and sport columns are required.  The remaining columns are all treated as named
+	// Roles, and we don't care if more columns are added.
+	columns := findEventColumns(headers, source.Columns, month, diagnostics)
 
-	missing := make(map[string]int)
+	statusHeader := cfg.GetWritebackConfiguration().StatusHeader
 
 	var sportingEvents []SportingEvent
 
-	for _, event := range eventData {
+	for index, event := range eventData {
+		// The header is row 1, so the first event is row 2.
+		rowNumber := index + 2
+
 		// Some rows are blank.  User should delete them, but let's be nice to users.
 		if len(event) == 0 {
 			continue
 		}
 
-		sportingEvent := buildSingleEvent(event, headers, requiredHeaders, nameToEmailMap, missing)
-		sportingEvents = append(sportingEvents, sportingEvent)
+		if missing, ok := columns.missing(event); ok {
+			diagnostics.Add(Diagnostic{
+				Rule:     RuleMissingFields,
+				Severity: SeverityError,
+				Tab:      month,
+				Row:      rowNumber,
+				Column:   missing.index + 1,
+				Message:  fmt.Sprintf("Row is missing the %s, so it isn't on the calendar", missing.header),
+				Fix:      "Fill in the date, time and sport, or delete the row",
+			})
+
+			continue
+		}
+
+		// Away games are in the venue's time zone, if it has one.
+		venueName := columns.venueName(event)
+		venue, knownVenue := cfg.GetVenue(venueName)
+		timeZone := cfg.venueTimeZone(venue)
+
+		sportingEvent, parsed := buildSingleEvent(event, month, rowNumber, headers, columns, timeZone, statusHeader,
+			nameToEmailMap, diagnostics)
+		sportingEvent.Spreadsheet = source.Name
+		sportingEvent.Location = cfg.GetSportLocation(sportingEvent.Sport)
+
+		switch {
+		case knownVenue && venue.Location != "":
+			sportingEvent.Location = venue.Location
+		case venueName != "":
+			sportingEvent.Location = venueName
+		}
+
+		if venueName != "" && !knownVenue {
+			diagnostics.Add(venueDiagnostic(venueName, timeZone, month, rowNumber, columns.venue.index+1))
+		}
+
+		if diagnostic, found := cfg.sportDiagnostic(sportingEvent.Sport, month, rowNumber, columns.sport.index+1); found {
+			diagnostics.Add(diagnostic)
+		}
+
+		// A row whose date or time can't be understood is already reported, and would otherwise look like
+		// a duplicate of every other such row for the sport.
+		if parsed {
+			sportingEvents = append(sportingEvents, sportingEvent)
+		}
+	}
+
+	return sportingEvents, nil
+}
+
+// findEventColumns Find the date, time and sport columns by their headers.  A header that isn't there
+// is a problem, and the column the sync has always used for it (A, B or C) is assumed instead.
+func findEventColumns(
+	headers []interface{},
+	mapping ColumnMapping,
+	month string,
+	diagnostics *Diagnostics) eventColumns {
+	find := func(expectedHeader string, fallback int) eventColumn {
+		for index, header := range headers {
+			if fmt.Sprint(header) == expectedHeader {
+				return eventColumn{header: expectedHeader, index: index}
+			}
+		}
+
+		found := ""
+		if fallback < len(headers) {
+			found = fmt.Sprint(headers[fallback])
+		}
+
+		diagnostics.Add(Diagnostic{
+			Rule:     RuleUnexpectedHeader,
+			Severity: SeverityError,
+			Tab:      month,
+			Row:      1,
+			Column:   fallback + 1,
+			Message:  fmt.Sprintf("Unexpected header.  Expected %s, found %q", expectedHeader, found),
+			Fix:      fmt.Sprintf("Change the header to %s", expectedHeader),
+		})
+
+		return eventColumn{header: expectedHeader, index: fallback}
+	}
+
+	// The venue column is optional, so it isn't a problem if there isn't one.
+	venue := eventColumn{header: mapping.Venue, index: -1}
+
+	for index, header := range headers {
+		if fmt.Sprint(header) == mapping.Venue {
+			venue.index = index
+		}
+	}
+
+	return eventColumns{
+		date:  find(mapping.Date, dateColumnNumber),
+		time:  find(mapping.Time, timeColumnNumber),
+		sport: find(mapping.Sport, sportColumnNumber),
+		venue: venue,
 	}
+}
 
-	return sportingEvents
+// missing The first of the date, time and sport that's past the end of the row.  The sheets API
+// leaves off trailing empty cells, so these are the ones left blank.
+func (columns eventColumns) missing(event []interface{}) (eventColumn, bool) {
+	for _, column := range []eventColumn{columns.date, columns.time, columns.sport} {
+		if column.index >= len(event) {
+			return column, true
+		}
+	}
+
+	return eventColumn{}, false
+}
+
+// isEventColumn Whether the column is the date, time, sport or venue, rather than a role.
+func (columns eventColumns) isEventColumn(index int) bool {
+	return index == columns.date.index || index == columns.time.index || index == columns.sport.index ||
+		index == columns.venue.index
+}
+
+// venueName Where the event is, or "" if it's at home.
+func (columns eventColumns) venueName(event []interface{}) string {
+	if columns.venue.index < 0 || columns.venue.index >= len(event) {
+		return ""
+	}
+
+	return strings.TrimSpace(fmt.Sprint(event[columns.venue.index]))
 }
 
 func buildSingleEvent(
 	event []interface{},
+	month string,
+	rowNumber int,
 	headers []interface{},
-	requiredHeaders []string,
+	columns eventColumns,
+	timeZone *time.Location,
+	statusHeader string,
 	nameToEmailMap map[string]string,
-	missing map[string]int) SportingEvent {
-	dateString := event[dateColumnNumber].(string)
-	timeString := event[timeColumnNumber].(string)
+	diagnostics *Diagnostics) (SportingEvent, bool) {
+	dateString := fmt.Sprint(event[columns.date.index])
+	timeString := fmt.Sprint(event[columns.time.index])
 
-	sportingEvent := SportingEvent{}
+	sportingEvent := SportingEvent{Tab: month, Row: rowNumber}
 
-	sportingEvent.Datetime = resolveDatetime(dateString, timeString)
-	sportingEvent.Sport = event[sportColumnNumber].(string)
+	datetime, err := resolveDatetime(dateString, timeString, timeZone)
+	if err != nil {
+		diagnostics.Add(datetimeDiagnostic(month, rowNumber, columns, dateString, timeString))
+	}
 
-	if len(headers) != len(event) {
-		log.Printf("No MATCH: %s\n", event)
+	sportingEvent.Datetime = datetime
+	sportingEvent.Sport = fmt.Sprint(event[columns.sport.index])
+
+	// The sheets API leaves off trailing empty cells, so a short row is normal.  A long row has
+	// assignments in columns with no role name.
+	if len(event) > len(headers) {
+		diagnostics.Add(Diagnostic{
+			Rule:     RuleRowLength,
+			Severity: SeverityWarning,
+			Tab:      month,
+			Row:      rowNumber,
+			Column:   len(headers) + 1,
+			Message: fmt.Sprintf("Row has %d cells but there are only %d headers, so the names past column %s are ignored",
+				len(event), len(headers), ColumnName(len(headers))),
+			Fix: "Add the role name in row 1, or move the names under a role",
+		})
 	}
 
 	for index, eventEntry := range event {
-		if index < len(requiredHeaders) {
+		if columns.isEventColumn(index) || index >= len(headers) {
 			continue
 		}
 
@@ -124,20 +365,37 @@ func buildSingleEvent(
 			continue
 		}
 
+		// The writeback status column isn't a role, even if writeback has since been turned off.
+		if fmt.Sprint(headers[index]) == statusHeader {
+			continue
+		}
+
 		name := eventEntry.(string)
+		sportingEvent.Assignments = append(sportingEvent.Assignments, Assignment{
+			Role:   fmt.Sprintf("%s", headers[index]),
+			Worker: name,
+			Email:  nameToEmailMap[name],
+		})
 
 		if nameToEmailMap[name] == "" {
-			missing[name]++
+			diagnostics.AddUnknownWorker(month, rowNumber, index+1, name, unknownWorkerFix(name, nameToEmailMap))
 		} else {
 			sportingEvent.Emails = append(sportingEvent.Emails, nameToEmailMap[name])
 			sportingEvent.Roles = append(sportingEvent.Roles, fmt.Sprintf("%s: %s", headers[index], name))
 		}
 	}
 
-	return sportingEvent
+	// The rest of the row is still checked, so all of its problems are reported at once.
+	return sportingEvent, err == nil
 }
 
-func resolveDatetime(dateString string, timeString string) time.Time {
+// resolveDatetime Parse the date and time in the time zone, unless the time says which zone it's in,
+// like "7 PM CT".
+func resolveDatetime(dateString string, timeString string, timeZone *time.Location) (time.Time, error) {
+	if location, rest, found := cutZoneSuffix(timeString); found {
+		timeZone, timeString = location, rest
+	}
+
 	// The times seem to be entered as "1 p.m.".  Let's normalize them a bit before proceeding.
 	// TODO - Spreadsheet should store "(DH)" in the sport instead of in the Time column.
 	replacer := strings.NewReplacer(".", "", " ", "", "TBA", "12:00am", "(DH)", "", "PM", "pm", "AM", "am")
@@ -150,53 +408,164 @@ func resolveDatetime(dateString string, timeString string) time.Time {
 	}
 
 	datetimeString := fmt.Sprintf("%s %s", dateString, timeString)
-	// Hardcode the parsing to be for Eastern time zone.  America/New_York handles daylight savings time adjustments.
-	eastern, _ := time.LoadLocation("America/New_York")
-	datetime, err := time.ParseInLocation("Monday, January 2, 2006 3:04pm", datetimeString, eastern)
+	// A location, rather than a fixed offset, handles daylight saving time.
+	return time.ParseInLocation(spreadsheetDateLayout+" "+spreadsheetTimeLayout, datetimeString, timeZone)
+}
 
-	if err != nil {
-		log.Printf("Error formatting date %s: %v\n", datetimeString, err)
+// datetimeDiagnostic Point at the date if that's what can't be understood, otherwise at the time.
+func datetimeDiagnostic(
+	month string,
+	rowNumber int,
+	columns eventColumns,
+	dateString string,
+	timeString string) Diagnostic {
+	diagnostic := Diagnostic{
+		Rule:     RuleUnparsableDatetime,
+		Severity: SeverityError,
+		Tab:      month,
+		Row:      rowNumber,
+	}
+
+	if _, err := time.Parse(spreadsheetDateLayout, dateString); err != nil {
+		diagnostic.Column = columns.date.index + 1
+		diagnostic.Message = fmt.Sprintf("Unable to understand the date %q", dateString)
+		diagnostic.Fix = "Use a date like Saturday, September 7, 2024"
+	} else {
+		diagnostic.Column = columns.time.index + 1
+		diagnostic.Message = fmt.Sprintf("Unable to understand the time %q", timeString)
+		diagnostic.Fix = "Use a time like 1 p.m., 7:30 p.m., 7 p.m. CT or TBA"
 	}
 
-	return datetime
+	return diagnostic
 }
 
-// The spreadsheet has a separate tab for worker contact info.  Let's pull the emails for calendar invites.
-func loadNameToEmailMap(srv *sheets.Service, spreadsheetID string) map[string]string {
-	rows := loadSpreadsheetRows(srv, spreadsheetID, "Worker Contact Info!A2:C")
+// unknownWorkerFix Suggest the directory entry the name was probably meant to be, if there is one.
+func unknownWorkerFix(name string, nameToEmailMap map[string]string) string {
+	for knownName := range nameToEmailMap {
+		if strings.EqualFold(strings.Join(strings.Fields(name), " "), knownName) {
+			return fmt.Sprintf("Change the name to %s, as it is in the %s tab", knownName, WorkerContactInfoTab)
+		}
+	}
 
-	nameToEmailMap := make(map[string]string)
+	return fmt.Sprintf("Add %s to the %s tab, or use their name as it is there", name, WorkerContactInfoTab)
+}
 
-	for _, row := range rows {
-		if len(row) != 1 {
-			name := row[0].(string)
-			email := row[2].(string)
+// LoadWorkers Get the entries in the Worker Contact Info tab of every spreadsheet that has one.  Their
+// email addresses are used for calendar invites.
+func LoadWorkers(
+	ctx context.Context,
+	cfg *Config,
+	srv *sheets.Service,
+	diagnostics *Diagnostics) (_ []Worker, err error) {
+	ctx, span := StartSpan(ctx, "LoadWorkers")
+	defer func() { EndSpan(span, err) }()
 
-			if strings.Contains(email, "@brown.edu") {
-				// Workers with Brown email addresses are often referred to on the schedule by first name only.
-				// So we put them into the map by first name as well as by full name.
-				firstName := strings.Split(name, " ")[0]
-				if nameToEmailMap[firstName] != "" {
-					log.Printf("Duplicate entry for %s\n", firstName)
-				}
+	var workers []Worker
 
-				nameToEmailMap[firstName] = email
-			}
+	for _, source := range cfg.GetSpreadsheetSources() {
+		if source.SkipWorkers {
+			continue
+		}
 
-			nameToEmailMap[name] = email
+		diagnostics.SetSpreadsheet(source.Name)
 
-			rows = append(rows, row)
+		sourceWorkers, err := loadSourceWorkers(ctx, srv, source, diagnostics)
+		if err != nil {
+			return nil, err
 		}
+
+		workers = append(workers, sourceWorkers...)
+	}
+
+	diagnostics.SetSpreadsheet("")
+
+	return workers, nil
+}
+
+func loadSourceWorkers(
+	ctx context.Context,
+	srv *sheets.Service,
+	source SpreadsheetSource,
+	diagnostics *Diagnostics) ([]Worker, error) {
+	rows, err := loadSpreadsheetRows(ctx, srv, source.SpreadsheetID, quoteTabName(WorkerContactInfoTab)+"!A2:C")
+	if err != nil {
+		return nil, err
+	}
+
+	var workers []Worker
+
+	for index, row := range rows {
+		// The header is row 1, so the first worker is row 2.
+		rowNumber := index + 2
+
+		if len(row) == 0 {
+			continue
+		}
+
+		name := fmt.Sprint(row[0])
+
+		if len(row) < 3 || fmt.Sprint(row[2]) == "" {
+			diagnostics.Add(Diagnostic{
+				Rule:     RuleMissingEmail,
+				Severity: SeverityWarning,
+				Tab:      WorkerContactInfoTab,
+				Row:      rowNumber,
+				Column:   3,
+				Message:  fmt.Sprintf("%s has no email address, so they won't be invited", name),
+				Fix:      fmt.Sprintf("Add %s's email address", name),
+			})
+
+			continue
+		}
+
+		workers = append(workers, Worker{Name: name, Email: fmt.Sprint(row[2]), Spreadsheet: source.Name, Row: rowNumber})
+	}
+
+	return workers, nil
+}
+
+// BuildWorkerDirectory Get a map of worker name to email address.
+func BuildWorkerDirectory(workers []Worker, diagnostics *Diagnostics) map[string]string {
+	nameToEmailMap := make(map[string]string)
+
+	for _, worker := range workers {
+		if strings.Contains(worker.Email, "@brown.edu") {
+			// Workers with Brown email addresses are often referred to on the schedule by first name only.
+			// So we put them into the map by first name as well as by full name.
+			// The same worker can be listed on more than one spreadsheet, which isn't ambiguous.
+			firstName := strings.Split(worker.Name, " ")[0]
+			if existing := nameToEmailMap[firstName]; existing != "" && existing != worker.Email {
+				diagnostics.Add(Diagnostic{
+					Rule:        RuleAmbiguousFirstName,
+					Severity:    SeverityWarning,
+					Spreadsheet: worker.Spreadsheet,
+					Tab:         WorkerContactInfoTab,
+					Row:         worker.Row,
+					Column:      1,
+					Message: fmt.Sprintf("Another worker is also called %s, so the first name alone is ambiguous",
+						firstName),
+					Fix: fmt.Sprintf("Use %s's full name on the schedule", worker.Name),
+				})
+			}
+
+			nameToEmailMap[firstName] = worker.Email
+		}
+
+		nameToEmailMap[worker.Name] = worker.Email
 	}
 
 	return nameToEmailMap
 }
 
-func loadSpreadsheetRows(srv *sheets.Service, spreadsheetID string, readRange string) [][]interface{} {
-	resp, err := srv.Spreadsheets.Values.Get(spreadsheetID, readRange).Do()
+func loadSpreadsheetRows(
+	ctx context.Context,
+	srv *sheets.Service,
+	spreadsheetID string,
+	readRange string) ([][]interface{}, error) {
+	resp, err := srv.Spreadsheets.Values.Get(spreadsheetID, readRange).Context(ctx).Do()
 	if err != nil {
-		log.Fatalf("Unable to retrieve data from sheet: %v", err)
+		return nil, fmt.Errorf("unable to retrieve %s from sheet: %w", readRange, err)
 	}
 
-	return resp.Values
+	return resp.Values, nil
 }
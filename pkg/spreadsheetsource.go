@@ -0,0 +1,140 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Events can come from more than one spreadsheet, like one per academic year and another for club
+// sports.  Each spreadsheet is a source with its own tabs and column headers, and the events from
+// all of them are merged, with a finding for any event that more than one row produces.
+
+// MainSpreadsheet The name of the spreadsheet given by spreadsheetId.
+const MainSpreadsheet = "main"
+
+// SpreadsheetSource A spreadsheet to read events from.
+type SpreadsheetSource struct {
+	Name          string        `yaml:"name"`
+	SpreadsheetID string        `yaml:"spreadsheetId"`
+	Tabs          []string      `yaml:"tabs"`        // Defaults to the month tabs the sync doesn't skip
+	Columns       ColumnMapping `yaml:"columns"`     // Every column that isn't mapped is a role
+	SkipWorkers   bool          `yaml:"skipWorkers"` // For spreadsheets without a Worker Contact Info tab
+
+	defaultTabs bool
+}
+
+// ColumnMapping The headers of the columns with the date, time, sport and venue.
+type ColumnMapping struct {
+	Date  string `yaml:"date"`
+	Time  string `yaml:"time"`
+	Sport string `yaml:"sport"`
+	Venue string `yaml:"venue"` // Optional.  Without a venue column, every event is at home.
+}
+
+const (
+	defaultDateHeader  = "Date"
+	defaultTimeHeader  = "Time"
+	defaultSportHeader = "Sport"
+	defaultVenueHeader = "Venue"
+)
+
+// withDefaults Fill in the month tabs and the usual headers.
+func (source SpreadsheetSource) withDefaults() SpreadsheetSource {
+	if len(source.Tabs) == 0 {
+		source.defaultTabs = true
+
+		for _, month := range monthTabs {
+			if skippedMonthTabs[month] == "" {
+				source.Tabs = append(source.Tabs, month)
+			}
+		}
+	}
+
+	if source.Columns.Date == "" {
+		source.Columns.Date = defaultDateHeader
+	}
+
+	if source.Columns.Time == "" {
+		source.Columns.Time = defaultTimeHeader
+	}
+
+	if source.Columns.Sport == "" {
+		source.Columns.Sport = defaultSportHeader
+	}
+
+	if source.Columns.Venue == "" {
+		source.Columns.Venue = defaultVenueHeader
+	}
+
+	return source
+}
+
+// ValidationTabs The tabs the validator checks: the ones the sync reads, plus the month tabs it
+// skips if the source uses the default tabs.
+func (source SpreadsheetSource) ValidationTabs() []string {
+	if source.defaultTabs {
+		return monthTabs
+	}
+
+	return source.Tabs
+}
+
+// URL Where people can open the spreadsheet.
+func (source SpreadsheetSource) URL() string {
+	return fmt.Sprintf("https://docs.google.com/spreadsheets/d/%s", source.SpreadsheetID)
+}
+
+// SourcesKey Identifies the set of spreadsheets, for the sync state.  With just the main spreadsheet,
+// this is its ID, as it was before there could be more than one.
+func SourcesKey(sources []SpreadsheetSource) string {
+	ids := make([]string, 0, len(sources))
+	for _, source := range sources {
+		ids = append(ids, source.SpreadsheetID)
+	}
+
+	return strings.Join(ids, ",")
+}
+
+// MergeEvents Keep the first row that produces each event, and add a finding for every other row
+// that produces the same one.  Rows that agree are just duplicates, but rows that disagree about the
+// workers are an error, since only the first one makes it onto the calendar.
+func MergeEvents(sportingEvents []SportingEvent, diagnostics *Diagnostics) []SportingEvent {
+	merged := make([]SportingEvent, 0, len(sportingEvents))
+	seen := make(map[string]SportingEvent)
+
+	for _, sportingEvent := range sportingEvents {
+		first, found := seen[sportingEvent.GetKey()]
+		if !found {
+			seen[sportingEvent.GetKey()] = sportingEvent
+			merged = append(merged, sportingEvent)
+
+			continue
+		}
+
+		diagnostic := Diagnostic{
+			Rule:        RuleDuplicateEvent,
+			Severity:    SeverityWarning,
+			Spreadsheet: sportingEvent.Spreadsheet,
+			Tab:         sportingEvent.Tab,
+			Row:         sportingEvent.Row,
+			Message:     fmt.Sprintf("The same event is already at %s, so this row is ignored", eventLocation(first)),
+			Fix:         "Delete one of the rows",
+		}
+
+		if !sportingEvent.IsMostlyEqual(first) {
+			diagnostic.Severity = SeverityError
+			diagnostic.Message = fmt.Sprintf("The event is already at %s with different workers, "+
+				"so this row is ignored", eventLocation(first))
+			diagnostic.Fix = "Put the workers on one of the rows and delete the other, or fix the date, time or sport"
+		}
+
+		diagnostics.Add(diagnostic)
+	}
+
+	return merged
+}
+
+// eventLocation Where the event is on the spreadsheets, for messages.
+func eventLocation(sportingEvent SportingEvent) string {
+	return Diagnostic{Spreadsheet: sportingEvent.Spreadsheet, Tab: sportingEvent.Tab, Row: sportingEvent.Row}.Location()
+}
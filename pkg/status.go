@@ -0,0 +1,358 @@
+package pkg
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	htmltemplate "html/template"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The status board is what the serve command knows about recent runs, served as JSON and as a small
+// HTML dashboard, so people can see what the sync is doing without reading the logs.
+
+// How many upcoming events the HTML dashboard shows.  The JSON API returns all of them.
+const dashboardUpcomingLimit = 25
+
+const dashboardTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Game day calendar sync</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+.failed { color: #b00; }
+</style>
+</head>
+<body>
+<h1>Game day calendar sync</h1>
+
+<h2>Last run</h2>
+{{- with .LastRun}}
+<table>
+<tr><th>Run</th><td>{{.RunID}}</td></tr>
+<tr><th>Started</th><td>{{.Started.Format "Mon Jan 2 3:04:05pm MST"}}</td></tr>
+<tr><th>Finished</th><td>{{.Finished.Format "Mon Jan 2 3:04:05pm MST"}}</td></tr>
+{{- if .Error}}
+<tr><th>Result</th><td class="failed">Failed: {{.Error}}</td></tr>
+{{- else if .Skipped}}
+<tr><th>Result</th><td>Spreadsheet unchanged, nothing to do</td></tr>
+{{- else}}
+<tr><th>Result</th><td>{{.Missing}} added, {{.Updated}} updated, {{.Extra}} removed
+{{- if .Failed}} <span class="failed">({{.Failed}} failed)</span>{{end}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No runs yet.</p>
+{{- end}}
+<p>
+{{- if .LastSuccess.IsZero}}No successful runs since startup.
+{{- else}}Last successful run: {{.LastSuccess.Format "Mon Jan 2 3:04:05pm MST"}}.{{end}}
+{{- if not .NextRun.IsZero}} Next run: {{.NextRun.Format "Mon Jan 2 3:04:05pm MST"}}.{{end}}
+</p>
+
+<h2>Spreadsheet problems</h2>
+{{- if .Diagnostics}}
+<table>
+<tr><th>Severity</th><th>Where</th><th>Problem</th><th>Fix</th></tr>
+{{- range .Diagnostics}}
+<tr><td>{{.Severity}}</td><td>{{.Location}}</td><td>{{.Message}}</td><td>{{.Fix}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>None.</p>
+{{- end}}
+
+<h2>Unknown workers</h2>
+{{- if .UnknownWorkers}}
+<table>
+<tr><th>Name</th><th>Assignments</th></tr>
+{{- range .UnknownWorkers}}
+<tr><td>{{.Name}}</td><td>{{.Count}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>None.</p>
+{{- end}}
+
+<h2>Upcoming events</h2>
+{{- if .Upcoming}}
+<table>
+<tr><th>When</th><th>Sport</th><th>Workers</th></tr>
+{{- range .Upcoming}}
+<tr><td>{{.Datetime.Format "Mon Jan 2 3:04pm"}}</td><td>{{.Sport}}</td><td>
+{{- range $index, $assignment := .Assignments}}{{if $index}}<br>{{end}}{{.Role}}: {{.Worker}}{{end}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>None.</p>
+{{- end}}
+</body>
+</html>
+`
+
+// RunStatus The outcome of one sync run.  Missing, Extra and Updated are the events that were
+// missing from, extra on, or different on the calendar.
+type RunStatus struct {
+	RunID    string    `json:"runId"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Skipped  bool      `json:"skipped"`
+	Error    string    `json:"error,omitempty"`
+	Missing  int       `json:"missing"`
+	Extra    int       `json:"extra"`
+	Updated  int       `json:"updated"`
+	Failed   int       `json:"failed"`
+}
+
+type ScheduledEvent struct {
+	Datetime    time.Time             `json:"datetime"`
+	Sport       string                `json:"sport"`
+	Location    string                `json:"location"`
+	Assignments []ScheduledAssignment `json:"assignments"`
+}
+
+type ScheduledAssignment struct {
+	Role   string `json:"role"`
+	Worker string `json:"worker"`
+	Known  bool   `json:"known"` // Whether the worker is in the Worker Contact Info tab
+}
+
+func NewScheduledEvent(sportingEvent SportingEvent) ScheduledEvent {
+	scheduledEvent := ScheduledEvent{
+		Datetime:    sportingEvent.Datetime,
+		Sport:       sportingEvent.Sport,
+		Location:    sportingEvent.Location,
+		Assignments: []ScheduledAssignment{},
+	}
+
+	for _, assignment := range sportingEvent.Assignments {
+		scheduledEvent.Assignments = append(scheduledEvent.Assignments, ScheduledAssignment{
+			Role:   assignment.Role,
+			Worker: assignment.Worker,
+			Known:  assignment.Email != "",
+		})
+	}
+
+	return scheduledEvent
+}
+
+// NewRunStatus Summarize the changes a run made to the calendar.
+func NewRunStatus(runID string, started time.Time, finished time.Time, changes []SyncChange) RunStatus {
+	status := RunStatus{RunID: runID, Started: started, Finished: finished}
+
+	for _, change := range changes {
+		switch change.Action {
+		case SyncCreate:
+			status.Missing++
+		case SyncDelete:
+			status.Extra++
+		case SyncUpdate:
+			status.Updated++
+		}
+
+		if change.Err != nil {
+			status.Failed++
+		}
+	}
+
+	return status
+}
+
+// StatusBoard Safe to update from the sync loop while the HTTP server is reading it.
+type StatusBoard struct {
+	username string
+	password string
+	template *htmltemplate.Template
+
+	mutex       sync.Mutex
+	lastRun     *RunStatus
+	lastSuccess time.Time
+	nextRun     time.Time
+	diagnostics *Diagnostics
+	upcoming    []SportingEvent
+}
+
+// NewStatusBoard If username is empty, anyone who can reach the server can see the status.
+func NewStatusBoard(username string, password string) *StatusBoard {
+	return &StatusBoard{
+		username: username,
+		password: password,
+		template: htmltemplate.Must(htmltemplate.New("dashboard").Parse(dashboardTemplate)),
+	}
+}
+
+func (board *StatusBoard) RecordRun(status RunStatus) {
+	board.mutex.Lock()
+	defer board.mutex.Unlock()
+
+	board.lastRun = &status
+
+	if status.Error == "" && status.Failed == 0 {
+		board.lastSuccess = status.Finished
+	}
+}
+
+func (board *StatusBoard) RecordNextRun(next time.Time) {
+	board.mutex.Lock()
+	defer board.mutex.Unlock()
+
+	board.nextRun = next
+}
+
+// RecordSpreadsheet Remember what the latest read of the spreadsheet found.
+func (board *StatusBoard) RecordSpreadsheet(diagnostics *Diagnostics, upcoming []SportingEvent) {
+	sorted := make([]SportingEvent, len(upcoming))
+	copy(sorted, upcoming)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Datetime.Before(sorted[j].Datetime)
+	})
+
+	board.mutex.Lock()
+	defer board.mutex.Unlock()
+
+	board.diagnostics = diagnostics
+	board.upcoming = sorted
+}
+
+// Handler The dashboard at /, and the JSON API under /api/.
+func (board *StatusBoard) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/status", board.serveStatus)
+	mux.HandleFunc("/api/diagnostics", board.serveDiagnostics)
+	mux.HandleFunc("/api/workers/unknown", board.serveUnknownWorkers)
+	mux.HandleFunc("/api/schedule", board.serveSchedule)
+	mux.HandleFunc("/", board.serveDashboard)
+
+	return board.requireLogin(mux)
+}
+
+func (board *StatusBoard) requireLogin(next http.Handler) http.Handler {
+	if board.username == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		username, password, ok := request.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(username), []byte(board.username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(board.password)) != 1 {
+			writer.Header().Set("WWW-Authenticate", `Basic realm="go-brown-sports"`)
+			http.Error(writer, "login required", http.StatusUnauthorized)
+
+			return
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
+
+type statusResponse struct {
+	LastRun     *RunStatus `json:"lastRun"`
+	LastSuccess time.Time  `json:"lastSuccess"`
+	NextRun     time.Time  `json:"nextRun"`
+}
+
+func (board *StatusBoard) serveStatus(writer http.ResponseWriter, _ *http.Request) {
+	board.mutex.Lock()
+	response := statusResponse{LastRun: board.lastRun, LastSuccess: board.lastSuccess, NextRun: board.nextRun}
+	board.mutex.Unlock()
+
+	writeJSON(writer, response)
+}
+
+func (board *StatusBoard) serveDiagnostics(writer http.ResponseWriter, _ *http.Request) {
+	problems, _ := board.getDiagnostics()
+
+	writeJSON(writer, problems)
+}
+
+func (board *StatusBoard) serveUnknownWorkers(writer http.ResponseWriter, _ *http.Request) {
+	_, unknownWorkers := board.getDiagnostics()
+
+	writeJSON(writer, unknownWorkers)
+}
+
+func (board *StatusBoard) serveSchedule(writer http.ResponseWriter, _ *http.Request) {
+	writeJSON(writer, board.getUpcoming(time.Now()))
+}
+
+func (board *StatusBoard) serveDashboard(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != "/" {
+		http.NotFound(writer, request)
+
+		return
+	}
+
+	problems, unknownWorkers := board.getDiagnostics()
+
+	upcoming := board.getUpcoming(time.Now())
+	if len(upcoming) > dashboardUpcomingLimit {
+		upcoming = upcoming[:dashboardUpcomingLimit]
+	}
+
+	board.mutex.Lock()
+	data := struct {
+		LastRun        *RunStatus
+		LastSuccess    time.Time
+		NextRun        time.Time
+		Diagnostics    []Diagnostic
+		UnknownWorkers []UnknownWorker
+		Upcoming       []ScheduledEvent
+	}{board.lastRun, board.lastSuccess, board.nextRun, problems, unknownWorkers, upcoming}
+	board.mutex.Unlock()
+
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := board.template.Execute(writer, data); err != nil {
+		slog.Error("Unable to render the dashboard", "error", err)
+	}
+}
+
+func (board *StatusBoard) getDiagnostics() ([]Diagnostic, []UnknownWorker) {
+	board.mutex.Lock()
+	defer board.mutex.Unlock()
+
+	// Empty lists rather than nulls, so API clients don't need to special case them.
+	if board.diagnostics == nil {
+		return []Diagnostic{}, []UnknownWorker{}
+	}
+
+	problems := append([]Diagnostic{}, board.diagnostics.Problems...)
+
+	return problems, board.diagnostics.GetUnknownWorkers()
+}
+
+// getUpcoming Events that started since the last run are left out.
+func (board *StatusBoard) getUpcoming(currentTime time.Time) []ScheduledEvent {
+	board.mutex.Lock()
+	defer board.mutex.Unlock()
+
+	scheduledEvents := []ScheduledEvent{}
+
+	for _, sportingEvent := range board.upcoming {
+		if !sportingEvent.Datetime.After(currentTime) {
+			continue
+		}
+
+		scheduledEvents = append(scheduledEvents, NewScheduledEvent(sportingEvent))
+	}
+
+	return scheduledEvents
+}
+
+func writeJSON(writer http.ResponseWriter, value any) {
+	writer.Header().Set("Content-Type", "application/json")
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(value); err != nil {
+		slog.Error("Unable to write a JSON response", "error", err)
+	}
+}
@@ -0,0 +1,133 @@
+package pkg
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type SyncAction string
+
+const (
+	SyncCreate SyncAction = "create"
+	SyncUpdate SyncAction = "update"
+	SyncDelete SyncAction = "delete"
+
+	// SyncExternal A change to one of our calendar events that this program didn't make.
+	SyncExternal SyncAction = "external"
+)
+
+// SyncChange One create, update or delete performed on the calendar during a sync run, or one
+// change made to a managed event by someone else.  Before is empty for a create, and After is
+// empty for a delete.
+type SyncChange struct {
+	Action          SyncAction
+	Key             string
+	Calendar        string // The name of the calendar route
+	CalendarEventID string
+	Before          SportingEvent
+	After           SportingEvent
+	Err             error
+}
+
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// NewRunID Generate an identifier that ties together everything done during a single run.
+func NewRunID() string {
+	const randomBytes = 4
+
+	suffix := make([]byte, randomBytes)
+	_, _ = rand.Read(suffix)
+
+	return fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102T150405Z"), hex.EncodeToString(suffix))
+}
+
+// DiffSportingEvents Get the field-level differences between two versions of an event.
+// Roles are compared by role name, so a swap of workers shows up as a single change.
+func DiffSportingEvents(before SportingEvent, after SportingEvent) []FieldChange {
+	var changes []FieldChange
+
+	beforeTime := formatEventTime(before.Datetime)
+	afterTime := formatEventTime(after.Datetime)
+
+	if beforeTime != afterTime {
+		changes = append(changes, FieldChange{Field: "datetime", Before: beforeTime, After: afterTime})
+	}
+
+	if before.Sport != after.Sport {
+		changes = append(changes, FieldChange{Field: "sport", Before: before.Sport, After: after.Sport})
+	}
+
+	if before.Location != after.Location {
+		changes = append(changes, FieldChange{Field: "location", Before: before.Location, After: after.Location})
+	}
+
+	beforeRoles := groupRoles(before.Roles)
+	afterRoles := groupRoles(after.Roles)
+
+	roleNames := make([]string, 0, len(beforeRoles)+len(afterRoles))
+	for roleName := range beforeRoles {
+		roleNames = append(roleNames, roleName)
+	}
+
+	for roleName := range afterRoles {
+		if _, ok := beforeRoles[roleName]; !ok {
+			roleNames = append(roleNames, roleName)
+		}
+	}
+
+	sort.Strings(roleNames)
+
+	for _, roleName := range roleNames {
+		if beforeRoles[roleName] != afterRoles[roleName] {
+			changes = append(changes, FieldChange{
+				Field:  "role " + roleName,
+				Before: beforeRoles[roleName],
+				After:  afterRoles[roleName],
+			})
+		}
+	}
+
+	return changes
+}
+
+// SplitRole Split a "Role: Worker" text representation into its parts.
+func SplitRole(role string) (string, string) {
+	roleName, worker, found := strings.Cut(role, ": ")
+	if !found {
+		return "", role
+	}
+
+	return roleName, worker
+}
+
+// groupRoles Map the role names to the worker(s) assigned to them.
+func groupRoles(roles []string) map[string]string {
+	grouped := make(map[string]string)
+
+	for _, role := range roles {
+		roleName, worker := SplitRole(role)
+		if grouped[roleName] != "" {
+			grouped[roleName] += ", "
+		}
+
+		grouped[roleName] += worker
+	}
+
+	return grouped
+}
+
+func formatEventTime(datetime time.Time) string {
+	if datetime.IsZero() {
+		return ""
+	}
+
+	return datetime.Format(time.RFC3339)
+}
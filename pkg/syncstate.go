@@ -0,0 +1,154 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"google.golang.org/api/drive/v3"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// The sync state remembers which revision of the spreadsheets the last successful sync saw, and a hash
+// of the settings it used.  Most runs find that nothing has changed, and one cheap Drive metadata
+// request lets us skip reading every month tab.
+
+type SpreadsheetRevision struct {
+	Version      string `json:"version"` // Drive increments this on every change to the file
+	ModifiedTime string `json:"modifiedTime"`
+}
+
+type SyncState struct {
+	SpreadsheetID string              `json:"spreadsheetId"` // All the spreadsheet IDs, if there's more than one
+	ConfigHash    string              `json:"configHash"`
+	Revision      SpreadsheetRevision `json:"revision"`
+	LastSuccess   time.Time           `json:"lastSuccess"`
+
+	path string
+}
+
+// GetSpreadsheetRevision Ask Drive for the current revision of the spreadsheet.
+func GetSpreadsheetRevision(
+	ctx context.Context,
+	driveService *drive.Service,
+	spreadsheetID string) (SpreadsheetRevision, error) {
+	file, err := driveService.Files.Get(spreadsheetID).Fields("version", "modifiedTime").SupportsAllDrives(true).
+		Context(ctx).Do()
+	if err != nil {
+		return SpreadsheetRevision{}, fmt.Errorf("unable to get the spreadsheet revision: %w", err)
+	}
+
+	return SpreadsheetRevision{Version: fmt.Sprint(file.Version), ModifiedTime: file.ModifiedTime}, nil
+}
+
+// GetSpreadsheetsRevision Get the revisions of all the spreadsheets as one, which changes whenever any
+// of them does.  With just one spreadsheet, this is its revision.
+func GetSpreadsheetsRevision(
+	ctx context.Context,
+	driveService *drive.Service,
+	sources []SpreadsheetSource) (SpreadsheetRevision, error) {
+	var combined SpreadsheetRevision
+
+	for index, source := range sources {
+		revision, err := GetSpreadsheetRevision(ctx, driveService, source.SpreadsheetID)
+		if err != nil {
+			return SpreadsheetRevision{}, fmt.Errorf("%s spreadsheet: %w", source.Name, err)
+		}
+
+		if index > 0 {
+			combined.Version += ","
+		}
+
+		combined.Version += revision.Version
+
+		// RFC 3339 times in UTC sort as strings.
+		if revision.ModifiedTime > combined.ModifiedTime {
+			combined.ModifiedTime = revision.ModifiedTime
+		}
+	}
+
+	return combined, nil
+}
+
+// LoadSyncState Read the state from the last successful sync.  If there isn't one, every revision
+// looks new.
+func LoadSyncState(path string, logger *slog.Logger) *SyncState {
+	state := &SyncState{path: path}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("Unable to read the sync state", "file", path, "error", err)
+		}
+
+		return state
+	}
+
+	if err = json.Unmarshal(content, state); err != nil {
+		logger.Warn("Unable to decode the sync state", "file", path, "error", err)
+
+		return &SyncState{path: path}
+	}
+
+	return state
+}
+
+// SyncConfigHash A hash of the settings that change what a sync does, so changing them (a new calendar
+// or time zone, say) makes the next run sync even if the spreadsheets haven't changed.
+func SyncConfigHash(cfg *Config) string {
+	settings := struct {
+		Spreadsheets []SpreadsheetSource
+		Calendars    []CalendarRoute
+		Sports       []Sport
+		Venues       []Venue
+		TimeZone     string
+		Writeback    WritebackConfiguration
+	}{
+		Spreadsheets: cfg.GetSpreadsheetSources(),
+		Calendars:    cfg.GetCalendarRoutes(),
+		Sports:       cfg.GetSports(),
+		Venues:       cfg.Venues,
+		TimeZone:     cfg.GetTimeZone().String(),
+		Writeback:    cfg.GetWritebackConfiguration(),
+	}
+
+	// These are plain values, which always encode.
+	content, _ := json.Marshal(settings)
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Unchanged Check whether the last successful sync already saw this revision of the spreadsheet, with
+// the same settings.
+func (state *SyncState) Unchanged(spreadsheetID string, configHash string, revision SpreadsheetRevision) bool {
+	return state.SpreadsheetID == spreadsheetID && state.ConfigHash == configHash && state.Revision.Version != "" &&
+		state.Revision == revision
+}
+
+// RecordSuccess Save the revision and settings that were just synced successfully.
+func (state *SyncState) RecordSuccess(
+	spreadsheetID string,
+	configHash string,
+	revision SpreadsheetRevision,
+	syncTime time.Time) error {
+	state.SpreadsheetID = spreadsheetID
+	state.ConfigHash = configHash
+	state.Revision = revision
+	state.LastSuccess = syncTime
+
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = writeFileAtomically(state.path, content); err != nil {
+		return fmt.Errorf("unable to save sync state: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package pkg
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// Several organizations can share one process.  Each tenant gets a whole Config of its own: whatever
+// it doesn't set comes from the top level of the config file, except for its spreadsheets, calendars,
+// credentials and files.  Its files default to a directory named after it, so tenants never share
+// state.
+
+// TenantConfig A tenant in the config file.  LoadConfig reads the rest of its settings into the
+// tenant's own Config.
+type TenantConfig struct {
+	Name string `yaml:"name"`
+
+	node yaml.Node
+}
+
+func (tenant *TenantConfig) UnmarshalYAML(node *yaml.Node) error {
+	var named struct {
+		Name string `yaml:"name"`
+	}
+
+	if err := node.Decode(&named); err != nil {
+		return err
+	}
+
+	tenant.Name = named.Name
+	tenant.node = *node
+
+	return nil
+}
+
+// GetTenants Get the config for each tenant, or just this config if there are no tenants.
+func (cfg *Config) GetTenants() []*Config {
+	if len(cfg.tenants) == 0 {
+		return []*Config{cfg}
+	}
+
+	return cfg.tenants
+}
+
+// GetTenantName The tenant this config is for, or "" if there are no tenants.
+func (cfg *Config) GetTenantName() string {
+	return cfg.tenant
+}
+
+// GetLogger A logger that says which tenant it's for, if there are tenants.
+func (cfg *Config) GetLogger() *slog.Logger {
+	if cfg.tenant != "" {
+		return slog.With("tenant", cfg.tenant)
+	}
+
+	return slog.Default()
+}
+
+// MakeTenantDirectory Create the directory the tenant's files go in by default.  Like every default
+// path, it's relative to the current directory, not to the config file.
+func (cfg *Config) MakeTenantDirectory() error {
+	if cfg.tenant == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(cfg.tenant, 0o700); err != nil {
+		return fmt.Errorf("unable to create the directory for tenant %s: %w", cfg.tenant, err)
+	}
+
+	return nil
+}
+
+// loadTenants Read each tenant's settings over the top-level ones.
+func (cfg *Config) loadTenants() error {
+	for _, tenant := range cfg.Tenants {
+		tenantCfg := cfg.tenantDefaults(tenant.Name)
+
+		if err := tenant.node.Decode(tenantCfg); err != nil {
+			return fmt.Errorf("unable to decode tenant %s: %w", tenant.Name, err)
+		}
+
+		cfg.tenants = append(cfg.tenants, tenantCfg)
+	}
+
+	return nil
+}
+
+// tenantDefaults The top-level settings, less the ones each tenant has to have its own of.
+func (cfg *Config) tenantDefaults(name string) *Config {
+	tenantCfg := *cfg
+	tenantCfg.tenant = name
+	tenantCfg.Tenants = nil
+	tenantCfg.tenants = nil
+
+	tenantCfg.CalendarID = ""
+	tenantCfg.SpreadsheetID = ""
+	tenantCfg.Spreadsheets = nil
+	tenantCfg.Calendars = nil
+	tenantCfg.Auth.KeyPath = ""
+	tenantCfg.Auth.Subject = ""
+
+	tenantCfg.ArchivePath = ""
+	tenantCfg.AuditLogPath = ""
+	tenantCfg.CalendarCachePath = ""
+	tenantCfg.SyncStatePath = ""
+	tenantCfg.CredentialsPath = ""
+	tenantCfg.TokenPath = ""
+	tenantCfg.LockPath = ""
+	tenantCfg.Reminders.StorePath = ""
+
+	return &tenantCfg
+}
+
+// tenantPath Where a file goes by default: in the tenant's directory, if this is a tenant.
+func (cfg *Config) tenantPath(path string) string {
+	if cfg.tenant == "" {
+		return path
+	}
+
+	return filepath.Join(cfg.tenant, path)
+}
+
+// validateTenants Check the top level for settings that belong in the tenants, and each tenant for
+// everything a config without tenants is checked for.
+func (cfg *Config) validateTenants(requireIDs bool) []error {
+	var problems []error
+
+	if cfg.CalendarID != "" || cfg.SpreadsheetID != "" || len(cfg.Spreadsheets) > 0 || len(cfg.Calendars) > 0 {
+		problems = append(problems, fmt.Errorf("with tenants, the spreadsheets and calendars go in each tenant"))
+	}
+
+	names := make(map[string]bool)
+
+	for index, tenant := range cfg.Tenants {
+		where := fmt.Sprintf("tenants[%d]", index)
+		if tenant.Name != "" {
+			where = fmt.Sprintf("tenants %s", tenant.Name)
+		}
+
+		switch {
+		case tenant.Name == "":
+			problems = append(problems, fmt.Errorf("%s has no name", where))
+		case names[tenant.Name]:
+			problems = append(problems, fmt.Errorf("%s: the name %s is already used", where, tenant.Name))
+		case !routeNamePattern.MatchString(tenant.Name):
+			problems = append(problems, fmt.Errorf("%s: the name can only have letters, digits, - and _", where))
+		}
+
+		names[tenant.Name] = true
+
+		tenantCfg := cfg.tenants[index]
+		if len(tenantCfg.Tenants) > 0 {
+			problems = append(problems, fmt.Errorf("%s can't have tenants of its own", where))
+		}
+
+		for _, problem := range tenantCfg.problems(requireIDs) {
+			problems = append(problems, fmt.Errorf("%s: %w", where, problem))
+		}
+	}
+
+	return problems
+}
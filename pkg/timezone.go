@@ -0,0 +1,103 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	_ "time/tzdata" // The time zone database, for hosts that don't have one
+)
+
+// Events are in the configured time zone, unless they're at a venue with its own time zone or the
+// time says which zone it's in, like "7 PM CT".  Each event keeps its zone, so the calendar shows an
+// away game at the local time wherever it is.
+
+// legacyTimeZone Calendar events without a time zone were made before it could be configured, when
+// everything was in Eastern time.
+const legacyTimeZone = "America/New_York"
+
+// Venue Where events are played.  Only needed for venues in another time zone, or whose address
+// should be on the calendar.
+type Venue struct {
+	Name     string `yaml:"name"`     // As it is in the venue column
+	Location string `yaml:"location"` // For the calendar.  Defaults to the name.
+	TimeZone string `yaml:"timeZone"` // An IANA time zone, like America/Chicago.  Defaults to timeZone.
+}
+
+// GetVenue Find the venue in the config.  Returns false if it isn't there.
+func (cfg *Config) GetVenue(name string) (Venue, bool) {
+	for _, venue := range cfg.Venues {
+		if strings.EqualFold(venue.Name, name) {
+			return venue, true
+		}
+	}
+
+	return Venue{}, false
+}
+
+// venueTimeZone The time zone for events at the venue.
+func (cfg *Config) venueTimeZone(venue Venue) *time.Location {
+	if venue.TimeZone != "" {
+		if location, err := time.LoadLocation(venue.TimeZone); err == nil {
+			return location
+		}
+	}
+
+	return cfg.GetTimeZone()
+}
+
+// venueDiagnostic An event at a venue that isn't in the config is in the configured time zone, which
+// may not be right.
+func venueDiagnostic(venueName string, timeZone *time.Location, month string, rowNumber int, column int) Diagnostic {
+	return Diagnostic{
+		Rule:     RuleUnknownVenue,
+		Severity: SeverityWarning,
+		Tab:      month,
+		Row:      rowNumber,
+		Column:   column,
+		Message:  fmt.Sprintf("%s isn't one of the venues in the config, so the time is in %s", venueName, timeZone),
+		Fix:      fmt.Sprintf("Add %s to the venues in the config, or add the time zone to the time: 7 p.m. CT", venueName),
+	}
+}
+
+// zoneSuffixPattern US time zone abbreviations, with or without standard or daylight: ET, EST and EDT
+// are all Eastern time.  People write EST all year, so it's the zone rather than the offset.
+var zoneSuffixPattern = regexp.MustCompile(`(?i)\b(E|C|M|P|AK|H)[SD]?T\b`)
+
+var zoneAbbreviations = map[string]string{
+	"E":  "America/New_York",
+	"C":  "America/Chicago",
+	"M":  "America/Denver",
+	"P":  "America/Los_Angeles",
+	"AK": "America/Anchorage",
+	"H":  "Pacific/Honolulu",
+}
+
+// cutZoneSuffix Take the time zone off a time like "7 PM CT".  Returns false if there isn't one.
+func cutZoneSuffix(timeString string) (*time.Location, string, bool) {
+	match := zoneSuffixPattern.FindStringSubmatchIndex(timeString)
+	if match == nil {
+		return nil, timeString, false
+	}
+
+	location, err := time.LoadLocation(zoneAbbreviations[strings.ToUpper(timeString[match[2]:match[3]])])
+	if err != nil {
+		return nil, timeString, false
+	}
+
+	return location, timeString[:match[0]] + timeString[match[1]:], true
+}
+
+// calendarTimeZone The time zone of a calendar event we made.
+func calendarTimeZone(name string) *time.Location {
+	if name == "" {
+		name = legacyTimeZone
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		location, _ = time.LoadLocation(legacyTimeZone)
+	}
+
+	return location
+}
@@ -0,0 +1,44 @@
+package pkg
+
+import (
+	"testing"
+)
+
+func TestCutZoneSuffix(t *testing.T) {
+	tests := []struct {
+		timeString string
+		wantZone   string // "" if there's no zone
+		wantRest   string
+	}{
+		{"7 PM CT", "America/Chicago", "7 PM "},
+		{"7:30 pm est", "America/New_York", "7:30 pm "},
+		{"7 PM EDT", "America/New_York", "7 PM "},
+		{"1 PM MST", "America/Denver", "1 PM "},
+		{"1 PM PDT", "America/Los_Angeles", "1 PM "},
+		{"Noon AKST", "America/Anchorage", "Noon "},
+		{"6 PM HT", "Pacific/Honolulu", "6 PM "},
+		{"7 PM", "", "7 PM"},
+		{"7 PM GMT", "", "7 PM GMT"},
+		{"7 PM CXT", "", "7 PM CXT"},
+		{"7 PM APT", "", "7 PM APT"}, // Only a whole word is a zone
+		{"", "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.timeString, func(t *testing.T) {
+			location, rest, ok := cutZoneSuffix(test.timeString)
+
+			if ok != (test.wantZone != "") {
+				t.Fatalf("cutZoneSuffix(%q) found a zone = %v, want %v", test.timeString, ok, !ok)
+			}
+
+			if ok && location.String() != test.wantZone {
+				t.Errorf("cutZoneSuffix(%q) zone = %s, want %s", test.timeString, location, test.wantZone)
+			}
+
+			if rest != test.wantRest {
+				t.Errorf("cutZoneSuffix(%q) rest = %q, want %q", test.timeString, rest, test.wantRest)
+			}
+		})
+	}
+}
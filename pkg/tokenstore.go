@@ -0,0 +1,285 @@
+package pkg
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/oauth2"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Keeping the OAuth token on disk.  The token is saved again whenever it's refreshed, and can be
+// encrypted, since the refresh token is as good as a password for the calendar and spreadsheet.
+
+// ErrTokenRevoked The refresh token no longer works, so someone has to authorize us again.
+var ErrTokenRevoked = errors.New("the saved token has been revoked or has expired; " +
+	"run go-brown-sports auth login to authorize again")
+
+const encryptedTokenVersion = 1
+
+// scrypt parameters recommended for interactive logins.
+const (
+	scryptN       = 32768
+	scryptR       = 8
+	scryptP       = 1
+	scryptSaltLen = 16
+	tokenKeyLen   = 32 // AES-256
+)
+
+// encryptedToken The token file, when it's encrypted.  A plaintext token file is just the token's JSON.
+type encryptedToken struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// storedToken The token's JSON, plus the scopes Google granted, which the token itself doesn't keep.
+type storedToken struct {
+	oauth2.Token
+	Scope string `json:"scope,omitempty"`
+}
+
+// TokenStore Reads and writes the token file.  If there's a passphrase, the file is encrypted.
+type TokenStore struct {
+	path       string
+	passphrase string
+}
+
+// NewTokenStore Get the token store for path, encrypted if the config has a token key.
+func NewTokenStore(path string, auth AuthConfiguration) (*TokenStore, error) {
+	passphrase, err := getTokenPassphrase(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenStore{path: path, passphrase: passphrase}, nil
+}
+
+// getTokenPassphrase The key comes from the environment, or from a file only its owner can read.
+func getTokenPassphrase(config AuthConfiguration) (string, error) {
+	if config.TokenKey != "" {
+		return config.TokenKey, nil
+	}
+
+	if config.TokenKeyFile == "" {
+		return "", nil
+	}
+
+	contents, err := os.ReadFile(config.TokenKeyFile)
+	if err != nil {
+		return "", fmt.Errorf("unable to read token key file: %w", err)
+	}
+
+	passphrase := strings.TrimSpace(string(contents))
+	if passphrase == "" {
+		return "", fmt.Errorf("token key file %s is empty", config.TokenKeyFile)
+	}
+
+	return passphrase, nil
+}
+
+func (store *TokenStore) Path() string {
+	return store.path
+}
+
+// Load Read the token.  A plaintext file is still read when there's a key, and gets encrypted the
+// next time it's saved.
+func (store *TokenStore) Load() (*oauth2.Token, error) {
+	contents, err := os.ReadFile(store.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope encryptedToken
+	if err = json.Unmarshal(contents, &envelope); err != nil {
+		return nil, fmt.Errorf("unable to decode token: %w", err)
+	}
+
+	if envelope.Ciphertext != nil {
+		contents, err = store.decrypt(envelope)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var stored storedToken
+	if err = json.Unmarshal(contents, &stored); err != nil {
+		return nil, fmt.Errorf("unable to decode token: %w", err)
+	}
+
+	token := &stored.Token
+	if stored.Scope != "" {
+		token = withScopes(token, strings.Fields(stored.Scope))
+	}
+
+	return token, nil
+}
+
+// Save Write the token to a temporary file and rename it into place, so a crash can't leave a
+// half-written token behind.
+func (store *TokenStore) Save(token *oauth2.Token) error {
+	contents, err := json.Marshal(storedToken{Token: *token, Scope: strings.Join(TokenScopes(token), " ")})
+	if err != nil {
+		return fmt.Errorf("failure encoding token: %w", err)
+	}
+
+	if store.passphrase != "" {
+		if contents, err = store.encrypt(contents); err != nil {
+			return err
+		}
+	}
+
+	temporary, err := os.CreateTemp(filepath.Dir(store.path), filepath.Base(store.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("unable to cache oauth token: %w", err)
+	}
+	defer os.Remove(temporary.Name()) // Fails harmlessly once the file has been renamed
+
+	// CreateTemp already makes the file readable only by us, but be explicit about it.
+	if err = temporary.Chmod(0600); err == nil {
+		_, err = temporary.Write(contents)
+	}
+
+	if err == nil {
+		err = temporary.Sync()
+	}
+
+	if closeErr := temporary.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to cache oauth token: %w", err)
+	}
+
+	if err = os.Rename(temporary.Name(), store.path); err != nil {
+		return fmt.Errorf("unable to cache oauth token: %w", err)
+	}
+
+	return nil
+}
+
+func (store *TokenStore) encrypt(plaintext []byte) ([]byte, error) {
+	envelope := encryptedToken{Version: encryptedTokenVersion, Salt: make([]byte, scryptSaltLen)}
+	if _, err := rand.Read(envelope.Salt); err != nil {
+		return nil, fmt.Errorf("unable to generate salt: %w", err)
+	}
+
+	aead, err := store.newAEAD(envelope.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope.Nonce = make([]byte, aead.NonceSize())
+	if _, err = rand.Read(envelope.Nonce); err != nil {
+		return nil, fmt.Errorf("unable to generate nonce: %w", err)
+	}
+
+	envelope.Ciphertext = aead.Seal(nil, envelope.Nonce, plaintext, nil)
+
+	return json.Marshal(envelope)
+}
+
+func (store *TokenStore) decrypt(envelope encryptedToken) ([]byte, error) {
+	if store.passphrase == "" {
+		return nil, fmt.Errorf("%s is encrypted; set AUTH_TOKEN_KEY or auth.tokenKeyFile", store.path)
+	}
+
+	if envelope.Version != encryptedTokenVersion {
+		return nil, fmt.Errorf("unknown token file version %d", envelope.Version)
+	}
+
+	aead, err := store.newAEAD(envelope.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aead.Open(nil, envelope.Nonce, envelope.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt %s; is the token key right?", store.path)
+	}
+
+	return plaintext, nil
+}
+
+// newAEAD AES-GCM with a key derived from the passphrase and salt.
+func (store *TokenStore) newAEAD(salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(store.passphrase), salt, scryptN, scryptR, scryptP, tokenKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("unable to derive token key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// persistingTokenSource Gets access tokens with just the scopes a command needs, and saves each one,
+// along with any new refresh token Google hands out.  The saved token is shared by every command, and
+// the scopes saved with it are all the access any of them was granted, not just this access token's.
+// So its access token, which may have been narrowed for a different command, is never used; each
+// access token comes from refreshing with a narrower scope.
+type persistingTokenSource struct {
+	ctx    context.Context
+	config *oauth2.Config // The scopes to ask for.  With none, the token has everything granted.
+	store  *TokenStore
+	saved  *oauth2.Token
+	logger *slog.Logger
+
+	mutex   sync.Mutex
+	current *oauth2.Token
+}
+
+// NewPersistingTokenSource Get tokens for config's scopes from the saved token's refresh token, saving
+// each refreshed token to the store.
+func NewPersistingTokenSource(
+	ctx context.Context,
+	config *oauth2.Config,
+	store *TokenStore,
+	token *oauth2.Token,
+	logger *slog.Logger) oauth2.TokenSource {
+	return &persistingTokenSource{ctx: ctx, config: config, store: store, saved: token, logger: logger}
+}
+
+func (source *persistingTokenSource) Token() (*oauth2.Token, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	if source.current.Valid() {
+		return source.current, nil
+	}
+
+	if source.saved.RefreshToken == "" {
+		return nil, fmt.Errorf("%w (there's no refresh token)", ErrTokenRevoked)
+	}
+
+	token, err := refreshScoped(source.ctx, source.config, source.saved.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// The token we have still works for this run, so a failure to save it isn't fatal.
+	saved := withScopes(token, TokenScopes(source.saved))
+	if err := source.store.Save(saved); err != nil {
+		source.logger.Error("Unable to save the refreshed token", "file", source.store.Path(), "error", err)
+	} else {
+		source.saved = saved
+	}
+
+	source.current = token
+
+	return token, nil
+}
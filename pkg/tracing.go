@@ -0,0 +1,88 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OpenTelemetry spans around reading the spreadsheet, refreshing the calendar, computing the
+// differences and each calendar change, so we can see where the time goes in a slow run.  Google API
+// requests made with a span's context show up as child spans.
+
+const (
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+)
+
+const tracerName = "schwaller.org/go-brown-sports"
+
+// ConfigureTracing Set up the global tracer provider.  The returned function flushes any spans that
+// haven't been exported yet, and should be called before exiting.  With no exporter configured,
+// spans are dropped.
+func ConfigureTracing(ctx context.Context, config TracingConfiguration) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+
+	var err error
+
+	switch config.Exporter {
+	case "":
+		return func(context.Context) error { return nil }, nil
+	case TracingExporterOTLP:
+		// Without an endpoint, the standard OTEL_EXPORTER_OTLP_* environment variables apply.
+		var options []otlptracehttp.Option
+		if config.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(config.Endpoint))
+		}
+
+		if config.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+
+		exporter, err = otlptracehttp.New(ctx, options...)
+	case TracingExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q, expected otlp or stdout", config.Exporter)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to create %s trace exporter: %w", config.Exporter, err)
+	}
+
+	serviceName, err := resource.Merge(resource.Default(),
+		resource.NewSchemaless(attribute.String("service.name", config.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to describe the service for tracing: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(serviceName))
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return provider.Shutdown, nil
+}
+
+// StartSpan Start a span as a child of whatever span is in the context.
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// EndSpan End the span, marking it as failed if there was an error.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
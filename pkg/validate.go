@@ -0,0 +1,259 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"google.golang.org/api/sheets/v4"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Checking the whole spreadsheet, so the coordinator can fix it before the sync runs.
+
+const sarifVersion = "2.1.0"
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// ruleDescriptions What each rule checks, for the SARIF output.
+var ruleDescriptions = map[string]string{
+	RuleEmptyTab:           "A month tab has nothing on it",
+	RuleSkippedTab:         "A month tab is left out of the sync until it's fixed",
+	RuleUnexpectedHeader:   "The date, time and sport columns must have the expected headers",
+	RuleMissingFields:      "Every event needs a date, time and sport",
+	RuleUnparsableDatetime: "The date or time can't be understood",
+	RuleRowLength:          "A row has names in columns with no role header",
+	RuleUnknownWorker:      "A name on the schedule isn't in the Worker Contact Info tab",
+	RuleMissingEmail:       "A worker has no email address",
+	RuleAmbiguousFirstName: "Two workers with Brown email addresses have the same first name",
+	RuleDuplicateEvent:     "More than one row has the same date, time and sport",
+	RuleUnknownSport:       "A sport isn't in the sport catalogue in the config",
+	RuleUnknownVenue:       "A venue isn't in the config, so its time zone isn't known",
+}
+
+// ValidateSpreadsheet Parse the worker directories and every tab of every spreadsheet, including the
+// month tabs the sync skips, adding what's wrong to diagnostics.  Returns the events that were parsed.
+func ValidateSpreadsheet(
+	ctx context.Context,
+	cfg *Config,
+	sheetService *sheets.Service,
+	diagnostics *Diagnostics) (_ []SportingEvent, err error) {
+	ctx, span := StartSpan(ctx, "ValidateSpreadsheet")
+	defer func() { EndSpan(span, err) }()
+
+	nameToEmailMap, err := LoadWorkerDirectory(ctx, cfg, sheetService, diagnostics)
+	if err != nil {
+		return nil, err
+	}
+
+	var sportingEvents []SportingEvent
+
+	for _, source := range cfg.GetSpreadsheetSources() {
+		diagnostics.SetSpreadsheet(source.Name)
+
+		for _, tab := range source.ValidationTabs() {
+			if reason := skippedMonthTabs[tab]; reason != "" && source.defaultTabs {
+				diagnostics.Add(Diagnostic{
+					Rule:     RuleSkippedTab,
+					Severity: SeverityInfo,
+					Tab:      tab,
+					Message:  fmt.Sprintf("The sync skips this tab because %s", reason),
+					Fix:      "Fix the problems below, then ask for the tab to be added back to the sync",
+				})
+			}
+
+			tabEvents, err := LoadMonthAssignments(ctx, cfg, source, sheetService, tab, nameToEmailMap, diagnostics)
+			if err != nil {
+				return nil, err
+			}
+
+			sportingEvents = append(sportingEvents, tabEvents...)
+		}
+	}
+
+	diagnostics.SetSpreadsheet("")
+
+	return MergeEvents(sportingEvents, diagnostics), nil
+}
+
+// Location Where the problem is, in A1 notation: a cell, a whole row, or just the tab.  Problems in
+// a spreadsheet other than the main one start with its name.
+func (diagnostic Diagnostic) Location() string {
+	tab := quoteTabName(diagnostic.Tab)
+
+	if diagnostic.Spreadsheet != "" && diagnostic.Spreadsheet != MainSpreadsheet {
+		tab = diagnostic.Spreadsheet + ": " + tab
+	}
+
+	switch {
+	case diagnostic.Cell != "":
+		return tab + "!" + diagnostic.Cell
+	case diagnostic.Row != 0:
+		return fmt.Sprintf("%s!%d:%d", tab, diagnostic.Row, diagnostic.Row)
+	default:
+		return tab
+	}
+}
+
+// spreadsheetName Findings about the main spreadsheet don't always say so.
+func (diagnostic Diagnostic) spreadsheetName() string {
+	if diagnostic.Spreadsheet == "" {
+		return MainSpreadsheet
+	}
+
+	return diagnostic.Spreadsheet
+}
+
+// quoteTabName Quote a tab name for A1 notation, if it needs it.
+func quoteTabName(tab string) string {
+	if strings.ContainsAny(tab, " '!") {
+		return "'" + strings.ReplaceAll(tab, "'", "''") + "'"
+	}
+
+	return tab
+}
+
+// The subset of SARIF we fill in.
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties sarifProperties `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// sarifRegion Rows are lines and spreadsheet columns are columns.
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// sarifProperties SARIF's own fixes need the replacement text, which we can't always give, so the
+// suggested fix goes here instead.
+type sarifProperties struct {
+	Severity Severity `json:"severity"`
+	Fix      string   `json:"fix,omitempty"`
+}
+
+// WriteSARIF Write the findings as a SARIF log.  Each spreadsheet is an artifact, with rows as lines
+// and columns as columns, and the logical location says which tab.
+func WriteSARIF(writer io.Writer, sources []SpreadsheetSource, findings []Diagnostic) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "go-brown-sports", Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+
+	ruleIDs := make([]string, 0, len(ruleDescriptions))
+	for ruleID := range ruleDescriptions {
+		ruleIDs = append(ruleIDs, ruleID)
+	}
+
+	sort.Strings(ruleIDs)
+
+	for _, ruleID := range ruleIDs {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules,
+			sarifRule{ID: ruleID, ShortDescription: sarifMessage{Text: ruleDescriptions[ruleID]}})
+	}
+
+	spreadsheetURLs := make(map[string]string)
+	for _, source := range sources {
+		spreadsheetURLs[source.Name] = source.URL()
+	}
+
+	for _, finding := range findings {
+		location := sarifLocation{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: spreadsheetURLs[finding.spreadsheetName()]},
+			},
+			LogicalLocations: []sarifLogicalLocation{
+				{Name: finding.Tab, FullyQualifiedName: finding.Location(), Kind: finding.locationKind()},
+			},
+		}
+
+		if finding.Row != 0 {
+			location.PhysicalLocation.Region = &sarifRegion{StartLine: finding.Row, StartColumn: finding.Column}
+		}
+
+		run.Results = append(run.Results, sarifResult{
+			RuleID:     finding.Rule,
+			Level:      sarifLevel(finding.Severity),
+			Message:    sarifMessage{Text: finding.Message},
+			Locations:  []sarifLocation{location},
+			Properties: sarifProperties{Severity: finding.Severity, Fix: finding.Fix},
+		})
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{run}})
+}
+
+func (diagnostic Diagnostic) locationKind() string {
+	switch {
+	case diagnostic.Cell != "":
+		return "cell"
+	case diagnostic.Row != 0:
+		return "row"
+	default:
+		return "tab"
+	}
+}
+
+func sarifLevel(severity Severity) string {
+	if severity == SeverityInfo {
+		return "note"
+	}
+
+	return string(severity)
+}
@@ -0,0 +1,383 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/api/sheets/v4"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Writing what the sync did back into the spreadsheet, since that's where the coordinator looks.
+// Each row gets a status in the status column, and each cell with a problem gets a note.
+
+// writebackNotePrefix Marks the notes we wrote, so we can remove them once the problem is fixed
+// without touching anyone else's notes.
+const writebackNotePrefix = "go-brown-sports: "
+
+const (
+	rowStatusSynced        = "synced"
+	rowStatusError         = "error"
+	rowStatusUnknownWorker = "unknown worker"
+)
+
+var rowStatusColors = map[string]*sheets.Color{
+	rowStatusSynced:        {Red: 0.85, Green: 0.93, Blue: 0.83},
+	rowStatusUnknownWorker: {Red: 1, Green: 0.95, Blue: 0.8},
+	rowStatusError:         {Red: 0.96, Green: 0.8, Blue: 0.8},
+}
+
+// WritebackResult How many cells the writeback changed.
+type WritebackResult struct {
+	Statuses int
+	Notes    int
+}
+
+func (result WritebackResult) Changed() bool {
+	return result.Statuses > 0 || result.Notes > 0
+}
+
+type cellPosition struct {
+	row    int // Numbered from 1, like the spreadsheet
+	column int
+}
+
+// WriteBack Fill in the status column for every upcoming event and every row with an error in one
+// spreadsheet, and put the diagnostics in notes on their cells.  Only cells that need to change are
+// written, all in a single BatchUpdate, so a run that changes nothing doesn't create a new spreadsheet
+// revision.
+func WriteBack(
+	ctx context.Context,
+	srv *sheets.Service,
+	source SpreadsheetSource,
+	config WritebackConfiguration,
+	sportingEvents []SportingEvent,
+	changes []SyncChange,
+	diagnostics *Diagnostics,
+	currentTime time.Time) (result WritebackResult, err error) {
+	ctx, span := StartSpan(ctx, "WriteBack", attribute.String("spreadsheet", source.Name))
+	defer func() { EndSpan(span, err) }()
+
+	var sourceEvents []SportingEvent
+
+	for _, sportingEvent := range sportingEvents {
+		if sportingEvent.Spreadsheet == source.Name {
+			sourceEvents = append(sourceEvents, sportingEvent)
+		}
+	}
+
+	var findings []Diagnostic
+
+	for _, finding := range diagnostics.Findings() {
+		if finding.spreadsheetName() == source.Name {
+			findings = append(findings, finding)
+		}
+	}
+
+	statuses := rowStatuses(sourceEvents, changes, findings, currentTime)
+	notes := cellNotes(findings)
+
+	var tabs []string
+	if !source.SkipWorkers {
+		tabs = append(tabs, WorkerContactInfoTab)
+	}
+
+	tabs = append(tabs, source.Tabs...)
+
+	ranges := make([]string, 0, len(tabs))
+	for _, tab := range tabs {
+		ranges = append(ranges, quoteTabName(tab))
+	}
+
+	spreadsheet, err := srv.Spreadsheets.Get(source.SpreadsheetID).
+		Ranges(ranges...).
+		Fields("sheets(properties(sheetId,title,gridProperties(columnCount)),data(rowData(values(formattedValue,note))))").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return result, fmt.Errorf("unable to read the spreadsheet for writeback: %w", err)
+	}
+
+	var requests []*sheets.Request
+
+	for _, sheet := range spreadsheet.Sheets {
+		grid := newSheetGrid(sheet)
+
+		if sheet.Properties.Title != WorkerContactInfoTab {
+			statusRequests := grid.statusRequests(statuses[sheet.Properties.Title], config.StatusHeader)
+			result.Statuses += len(statusRequests)
+			requests = append(requests, statusRequests...)
+		}
+
+		noteRequests := grid.noteRequests(notes[sheet.Properties.Title])
+		result.Notes += len(noteRequests)
+		requests = append(requests, noteRequests...)
+	}
+
+	if len(requests) == 0 {
+		return result, nil
+	}
+
+	_, err = srv.Spreadsheets.BatchUpdate(source.SpreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{Requests: requests}).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return WritebackResult{}, fmt.Errorf("unable to write back to the spreadsheet: %w", err)
+	}
+
+	return result, nil
+}
+
+// rowStatuses Get the status for each row, by tab.  Errors win over unknown workers, which win
+// over synced.  Past events are left alone, since the sync doesn't touch them.
+func rowStatuses(
+	sportingEvents []SportingEvent,
+	changes []SyncChange,
+	findings []Diagnostic,
+	currentTime time.Time) map[string]map[int]string {
+	statuses := make(map[string]map[int]string)
+
+	setStatus := func(tab string, row int, status string) {
+		if statuses[tab] == nil {
+			statuses[tab] = make(map[int]string)
+		}
+
+		statuses[tab][row] = status
+	}
+
+	failures := make(map[string]SyncChange)
+
+	for _, change := range changes {
+		if change.Err != nil {
+			failures[change.Key] = change
+		}
+	}
+
+	for _, sportingEvent := range sportingEvents {
+		if sportingEvent.Tab == "" || !sportingEvent.Datetime.After(currentTime) {
+			continue
+		}
+
+		var unknownWorkers []string
+
+		for _, assignment := range sportingEvent.Assignments {
+			if assignment.Email == "" {
+				unknownWorkers = append(unknownWorkers, assignment.Worker)
+			}
+		}
+
+		switch failure, failed := failures[sportingEvent.GetKey()]; {
+		case failed && failure.Calendar != "" && failure.Calendar != MainCalendar:
+			setStatus(sportingEvent.Tab, sportingEvent.Row, fmt.Sprintf("%s: unable to %s the event on the %s calendar",
+				rowStatusError, failure.Action, failure.Calendar))
+		case failed:
+			setStatus(sportingEvent.Tab, sportingEvent.Row,
+				fmt.Sprintf("%s: unable to %s the calendar event", rowStatusError, failure.Action))
+		case len(unknownWorkers) > 0:
+			setStatus(sportingEvent.Tab, sportingEvent.Row,
+				fmt.Sprintf("%s: %s", rowStatusUnknownWorker, strings.Join(unknownWorkers, ", ")))
+		default:
+			setStatus(sportingEvent.Tab, sportingEvent.Row, rowStatusSynced)
+		}
+	}
+
+	// Rows with errors may not have made it into the events at all, or may be in the past because
+	// their date couldn't be understood.
+	for _, finding := range findings {
+		if finding.Severity == SeverityError && finding.Row > 1 {
+			setStatus(finding.Tab, finding.Row, fmt.Sprintf("%s: %s", rowStatusError, finding.Message))
+		}
+	}
+
+	return statuses
+}
+
+// cellNotes Get the note for each cell with a problem, by tab.
+func cellNotes(findings []Diagnostic) map[string]map[cellPosition]string {
+	notes := make(map[string]map[cellPosition]string)
+
+	for _, finding := range findings {
+		if finding.Row == 0 || finding.Column == 0 {
+			continue
+		}
+
+		if notes[finding.Tab] == nil {
+			notes[finding.Tab] = make(map[cellPosition]string)
+		}
+
+		text := finding.Message
+		if finding.Fix != "" {
+			text += "\nFix: " + finding.Fix
+		}
+
+		position := cellPosition{row: finding.Row, column: finding.Column}
+		if notes[finding.Tab][position] == "" {
+			notes[finding.Tab][position] = writebackNotePrefix + text
+		} else {
+			notes[finding.Tab][position] += "\n\n" + text
+		}
+	}
+
+	return notes
+}
+
+// sheetGrid What's already in a tab.
+type sheetGrid struct {
+	sheetID     int64
+	columnCount int64
+	rows        []*sheets.RowData
+}
+
+func newSheetGrid(sheet *sheets.Sheet) sheetGrid {
+	grid := sheetGrid{sheetID: sheet.Properties.SheetId}
+
+	if sheet.Properties.GridProperties != nil {
+		grid.columnCount = sheet.Properties.GridProperties.ColumnCount
+	}
+
+	if len(sheet.Data) > 0 {
+		grid.rows = sheet.Data[0].RowData
+	}
+
+	return grid
+}
+
+// cell Get the cell at row, column (numbered from 1), or an empty cell if there's nothing there.
+func (grid sheetGrid) cell(row int, column int) *sheets.CellData {
+	if row > len(grid.rows) || grid.rows[row-1] == nil || column > len(grid.rows[row-1].Values) {
+		return &sheets.CellData{}
+	}
+
+	if cell := grid.rows[row-1].Values[column-1]; cell != nil {
+		return cell
+	}
+
+	return &sheets.CellData{}
+}
+
+// statusColumn Find the status column by its header.  If there isn't one yet, it goes after the
+// last column anything is in, so it can't cover up a name in a column with no header.
+func (grid sheetGrid) statusColumn(statusHeader string) (column int, found bool) {
+	for _, row := range grid.rows {
+		if row != nil && len(row.Values) > column {
+			column = len(row.Values)
+		}
+	}
+
+	for index := 1; index <= column; index++ {
+		if grid.cell(1, index).FormattedValue == statusHeader {
+			return index, true
+		}
+	}
+
+	return column + 1, false
+}
+
+func (grid sheetGrid) statusRequests(statuses map[int]string, statusHeader string) []*sheets.Request {
+	if len(statuses) == 0 {
+		return nil
+	}
+
+	column, found := grid.statusColumn(statusHeader)
+
+	var requests []*sheets.Request
+
+	if !found {
+		if int64(column) > grid.columnCount {
+			requests = append(requests, &sheets.Request{AppendDimension: &sheets.AppendDimensionRequest{
+				SheetId:   grid.sheetID,
+				Dimension: "COLUMNS",
+				Length:    int64(column) - grid.columnCount,
+			}})
+		}
+
+		requests = append(requests, grid.updateCell(1, column,
+			&sheets.CellData{UserEnteredValue: &sheets.ExtendedValue{StringValue: &statusHeader}},
+			"userEnteredValue"))
+	}
+
+	for _, row := range sortedRows(statuses) {
+		status := statuses[row]
+		if grid.cell(row, column).FormattedValue == status {
+			continue
+		}
+
+		color, _, _ := strings.Cut(status, ":")
+
+		requests = append(requests, grid.updateCell(row, column,
+			&sheets.CellData{
+				UserEnteredValue:  &sheets.ExtendedValue{StringValue: &status},
+				UserEnteredFormat: &sheets.CellFormat{BackgroundColor: rowStatusColors[color]},
+			},
+			"userEnteredValue,userEnteredFormat.backgroundColor"))
+	}
+
+	return requests
+}
+
+// noteRequests Add or change the notes for problems, and remove our notes from cells that no
+// longer have a problem.
+func (grid sheetGrid) noteRequests(notes map[cellPosition]string) []*sheets.Request {
+	var requests []*sheets.Request
+
+	for rowIndex, row := range grid.rows {
+		if row == nil {
+			continue
+		}
+
+		for columnIndex, cell := range row.Values {
+			position := cellPosition{row: rowIndex + 1, column: columnIndex + 1}
+			if cell != nil && strings.HasPrefix(cell.Note, writebackNotePrefix) && notes[position] == "" {
+				requests = append(requests, grid.updateCell(position.row, position.column, &sheets.CellData{}, "note"))
+			}
+		}
+	}
+
+	positions := make([]cellPosition, 0, len(notes))
+	for position := range notes {
+		positions = append(positions, position)
+	}
+
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].row != positions[j].row {
+			return positions[i].row < positions[j].row
+		}
+
+		return positions[i].column < positions[j].column
+	})
+
+	for _, position := range positions {
+		if grid.cell(position.row, position.column).Note != notes[position] {
+			requests = append(requests,
+				grid.updateCell(position.row, position.column, &sheets.CellData{Note: notes[position]}, "note"))
+		}
+	}
+
+	return requests
+}
+
+// updateCell Write the fields of a single cell.  Rows and columns are numbered from 1.
+func (grid sheetGrid) updateCell(row int, column int, cell *sheets.CellData, fields string) *sheets.Request {
+	return &sheets.Request{UpdateCells: &sheets.UpdateCellsRequest{
+		Start: &sheets.GridCoordinate{
+			SheetId:     grid.sheetID,
+			RowIndex:    int64(row - 1),
+			ColumnIndex: int64(column - 1),
+		},
+		Rows:   []*sheets.RowData{{Values: []*sheets.CellData{cell}}},
+		Fields: fields,
+	}}
+}
+
+func sortedRows(statuses map[int]string) []int {
+	rows := make([]int, 0, len(statuses))
+	for row := range statuses {
+		rows = append(rows, row)
+	}
+
+	sort.Ints(rows)
+
+	return rows
+}
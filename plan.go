@@ -0,0 +1,88 @@
+// Copyright 2024 Peter Schwaller (peter@schwaller.org)
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"schwaller.org/go-brown-sports/pkg"
+	"time"
+)
+
+// runPlan Show what sync would change, without changing anything.  Unlike sync, the plan is always
+// worked out, even if the spreadsheet hasn't changed.
+func runPlan(args []string) int {
+	flags, options := newFlagSet("plan")
+	if exitCode, ok := options.parse(flags, args); !ok {
+		return exitCode
+	}
+
+	ctx, span := pkg.StartSpan(context.Background(), "plan")
+	defer span.End()
+
+	_, client, err := pkg.AccessGoogleClient(options.config, pkg.ProfileCalendar)
+	if err != nil {
+		return failure("Unable to create Google client", "error", err)
+	}
+
+	currentTime := time.Now()
+
+	spreadsheet, err := loadSpreadsheet(ctx, options.config, options.config.GetLogger(), client)
+	if err != nil {
+		return failure("Unable to read the spreadsheet", "error", err)
+	}
+
+	spreadsheetFutureEvents := pkg.FilterFutureEvents(spreadsheet.events, currentTime)
+
+	var plan []pkg.SyncChange
+
+	for _, route := range options.config.GetCalendarRoutes() {
+		// The refreshed calendar cache isn't saved, so the next sync still sees any outside changes.
+		_, calendarCache, _, err := loadCalendar(ctx, route, options.config.GetLogger(), client)
+		if err != nil {
+			return failure("Unable to read the calendar", "calendar", route.Name, "error", err)
+		}
+
+		calendarFutureEvents, calendarFutureEventIds := calendarCache.GetFutureEventMaps(currentTime)
+		routePlan := pkg.PlanChanges(ctx, route.FilterEvents(spreadsheetFutureEvents), calendarFutureEvents,
+			calendarFutureEventIds)
+		plan = append(plan, withCalendar(routePlan, route.Name)...)
+	}
+
+	entries := make([]pkg.AuditEntry, 0, len(plan))
+	for _, change := range plan {
+		entries = append(entries, pkg.NewAuditEntry("", change, currentTime))
+	}
+
+	if options.output == outputJSON {
+		if exitCode := printJSON(entries); exitCode != exitOK {
+			return exitCode
+		}
+	} else {
+		for _, entry := range entries {
+			fmt.Printf("%-6s  %s\n", entry.Action, eventLabel(entry))
+			printFieldChanges(entry)
+		}
+
+		fmt.Printf("%d to create, %d to delete, %d to update.\n",
+			countActions(plan, pkg.SyncCreate), countActions(plan, pkg.SyncDelete), countActions(plan, pkg.SyncUpdate))
+	}
+
+	if len(plan) > 0 {
+		return exitFindings
+	}
+
+	return exitOK
+}
+
+func countActions(changes []pkg.SyncChange, action pkg.SyncAction) int {
+	count := 0
+
+	for _, change := range changes {
+		if change.Action == action {
+			count++
+		}
+	}
+
+	return count
+}
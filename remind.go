@@ -0,0 +1,80 @@
+// Copyright 2024 Peter Schwaller (peter@schwaller.org)
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"schwaller.org/go-brown-sports/pkg"
+	"time"
+)
+
+func runRemind(args []string) int {
+	flags, options := newFlagSet("remind")
+	dryRun := flags.Bool("dry-run", false, "list the reminders that would be sent without sending them")
+
+	if exitCode, ok := options.parse(flags, args); !ok {
+		return exitCode
+	}
+
+	config := options.config.GetReminderConfiguration()
+
+	ctx, client, err := pkg.AccessGoogleClient(options.config, pkg.ProfileReadOnly)
+	if err != nil {
+		return failure("Unable to create Google client", "error", err)
+	}
+
+	spreadsheet, err := loadSpreadsheet(ctx, options.config, options.config.GetLogger(), client)
+	if err != nil {
+		return failure("Unable to load events", "error", err)
+	}
+
+	candidates := pkg.BuildReminders(spreadsheet.events, time.Now(), config.Window)
+
+	reminders, err := pkg.OpenReminders(config, options.config.GetSMTPConfiguration())
+	if err != nil {
+		return failure("Unable to send reminders", "error", err)
+	}
+	defer reminders.Close()
+
+	// With -dry-run, these are the reminders that would have been sent.
+	sent := []pkg.Reminder{}
+	exitCode := exitOK
+
+	for _, reminder := range candidates {
+		if reminders.WasSent(reminder) {
+			continue
+		}
+
+		if *dryRun {
+			sent = append(sent, reminder)
+
+			continue
+		}
+
+		if err = reminders.Send(reminder); err != nil {
+			slog.Error("Unable to send reminder",
+				"worker", reminder.Worker, "event_key", reminder.EventKey, "error", err)
+
+			exitCode = exitFailure
+
+			continue
+		}
+
+		sent = append(sent, reminder)
+	}
+
+	slog.Info("Reminders sent", "count", len(sent), "dry_run", *dryRun)
+
+	if options.output == outputJSON {
+		if printJSON(sent) != exitOK {
+			return exitFailure
+		}
+	} else if *dryRun {
+		for _, reminder := range sent {
+			fmt.Printf("%s: %s for %s\n", reminder.Email, reminder.Role, reminder.EventKey)
+		}
+	}
+
+	return exitCode
+}
@@ -0,0 +1,337 @@
+// Copyright 2024 Peter Schwaller (peter@schwaller.org)
+
+package main
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"schwaller.org/go-brown-sports/pkg"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// runServe Keep running, syncing every tenant on its own schedule, until we get SIGINT or SIGTERM.
+func runServe(args []string) int {
+	flags, options := newFlagSet("serve")
+
+	options.allTenants = true
+
+	if exitCode, ok := options.parse(flags, args); !ok {
+		return exitCode
+	}
+
+	// The context is only used to interrupt the wait between runs.  A run that's in progress when the
+	// signal arrives is allowed to finish, so we never leave the calendar half updated.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// The dashboard and metrics are served for the whole process, so they come from the top level of
+	// the config.
+	servers := httpServers{}
+	dashboardConfig := options.root.GetDashboardConfiguration()
+
+	if metricsConfig := options.root.GetMetricsConfiguration(); metricsConfig.Enabled {
+		servers.handle(metricsConfig.Listen, metricsConfig.Path, pkg.MetricsHandler())
+	}
+
+	daemons := make([]*daemon, 0, len(options.tenants))
+
+	for _, cfg := range options.tenants {
+		tenantDaemon, err := newDaemon(cfg, servers, dashboardConfig)
+		if err != nil {
+			return failure("Invalid daemon schedule", tenantArgs(cfg, "error", err)...)
+		}
+
+		daemons = append(daemons, tenantDaemon)
+	}
+
+	// The servers need to be listening before we ask Drive to start sending notifications.
+	servers.start(ctx)
+
+	slog.Info("Serving.  Press Ctrl-C to stop.")
+
+	// Each tenant runs on its own, so a slow or failing tenant doesn't hold up the others.
+	var wait sync.WaitGroup
+
+	for _, tenantDaemon := range daemons {
+		wait.Add(1)
+
+		go func(tenantDaemon *daemon) {
+			defer wait.Done()
+			tenantDaemon.run(ctx)
+		}(tenantDaemon)
+	}
+
+	wait.Wait()
+	slog.Info("Shutting down")
+
+	return exitOK
+}
+
+// daemon Syncs one tenant on its schedule.
+type daemon struct {
+	cfg      *pkg.Config
+	logger   *slog.Logger
+	schedule *pkg.CronSchedule
+	board    *pkg.StatusBoard
+	receiver *pkg.DriveNotificationReceiver
+
+	// Drive notifications (if enabled) arrive here.  One pending trigger is plenty.
+	spreadsheetChanged chan struct{}
+}
+
+// newDaemon Set up the tenant's dashboard and Drive notification receiver.  With tenants, each
+// tenant's dashboard is under its name, like /brown/.
+func newDaemon(
+	cfg *pkg.Config,
+	servers httpServers,
+	dashboardConfig pkg.DashboardConfiguration) (*daemon, error) {
+	tenantDaemon := &daemon{cfg: cfg, logger: cfg.GetLogger(), spreadsheetChanged: make(chan struct{}, 1)}
+
+	if schedule := cfg.GetDaemonConfiguration().Schedule; schedule != "" {
+		var err error
+
+		tenantDaemon.schedule, err = pkg.ParseCronSchedule(schedule)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if dashboardConfig.Enabled {
+		tenantDaemon.board = pkg.NewStatusBoard(dashboardConfig.Username, dashboardConfig.Password)
+
+		dashboardPath := cfg.GetDashboardPath()
+		servers.handle(dashboardConfig.Listen, dashboardPath,
+			http.StripPrefix(strings.TrimSuffix(dashboardPath, "/"), tenantDaemon.board.Handler()))
+	}
+
+	tenantDaemon.receiver = newDriveReceiver(cfg, servers, tenantDaemon.spreadsheetChanged)
+
+	return tenantDaemon, nil
+}
+
+// run Sync the tenant, then wait for the next run, until the context is done.
+func (tenantDaemon *daemon) run(ctx context.Context) {
+	cfg := tenantDaemon.cfg
+	config := cfg.GetDaemonConfiguration()
+	logger := tenantDaemon.logger
+
+	watchers := startDriveWatch(ctx, cfg, tenantDaemon.receiver)
+	defer watchers.Stop()
+
+	var lastResult syncResult
+
+	for {
+		started := time.Now()
+		result, err := syncOnce(cfg, false)
+
+		switch {
+		case errors.Is(err, pkg.ErrSyncInProgress):
+			logger.Info("Skipping run", "run_id", result.runID, "reason", err)
+		case err != nil:
+			logger.Error("Sync failed", "run_id", result.runID, "error", err)
+		case !result.skipped:
+			lastResult = result
+		}
+
+		if !errors.Is(err, pkg.ErrSyncInProgress) {
+			recordRun(cfg, tenantDaemon.board, started, result, err)
+		}
+
+		next := nextRunTime(time.Now(), config, tenantDaemon.schedule, lastResult.nextEvent)
+
+		// While Drive is telling us about changes, polling is just a safety net.
+		if watchers.Active() {
+			next = time.Now().Add(cfg.GetWatchConfiguration().PollInterval)
+		}
+
+		logger.Info("Next sync scheduled", "at", next.Format(time.RFC3339))
+
+		if tenantDaemon.board != nil {
+			tenantDaemon.board.RecordNextRun(next)
+		}
+
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return
+		case <-tenantDaemon.spreadsheetChanged:
+			timer.Stop()
+			logger.Info("Spreadsheet changed")
+		case <-timer.C:
+		}
+	}
+}
+
+// nextRunTime A cron schedule wins if there is one.  Otherwise use the regular interval, or the
+// tighter game day interval when an event is coming up soon.
+func nextRunTime(now time.Time, config pkg.DaemonConfiguration, schedule *pkg.CronSchedule, nextEvent time.Time) time.Time {
+	if schedule != nil {
+		if next := schedule.Next(now); !next.IsZero() {
+			return next
+		}
+	}
+
+	interval := config.Interval
+	if !nextEvent.IsZero() && nextEvent.Sub(now) < config.GameDayWindow && config.GameDayInterval < interval {
+		interval = config.GameDayInterval
+	}
+
+	return now.Add(interval)
+}
+
+// recordRun Put the outcome of a run into the metrics and on the dashboard (if there is one).  A
+// skipped run didn't read the spreadsheet, so both keep showing what the last full run found.
+func recordRun(cfg *pkg.Config, board *pkg.StatusBoard, started time.Time, result syncResult, err error) {
+	status := pkg.NewRunStatus(result.runID, started, time.Now(), result.changes)
+	status.Skipped = result.skipped
+
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	pkg.ObserveRun(cfg.GetTenantName(), status, result.changes, result.diagnostics)
+
+	if board == nil {
+		return
+	}
+
+	board.RecordRun(status)
+
+	if result.diagnostics != nil {
+		board.RecordSpreadsheet(result.diagnostics, result.upcoming)
+	}
+}
+
+// httpServers The handlers for each listen address, so the dashboard and the Drive notification
+// receiver can share a port.
+type httpServers map[string]*http.ServeMux
+
+func (servers httpServers) handle(listen string, pattern string, handler http.Handler) {
+	mux, ok := servers[listen]
+	if !ok {
+		mux = http.NewServeMux()
+		servers[listen] = mux
+	}
+
+	mux.Handle(pattern, handler)
+}
+
+// start Serve until the context is done.
+func (servers httpServers) start(ctx context.Context) {
+	for listen, mux := range servers {
+		server := &http.Server{Addr: listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("HTTP server stopped", "listen", server.Addr, "error", err)
+			}
+		}()
+
+		go func() {
+			<-ctx.Done()
+			_ = server.Close()
+		}()
+	}
+}
+
+// newDriveReceiver Set up the handler for Drive push notifications.  Returns nil if notifications
+// aren't enabled.
+func newDriveReceiver(
+	cfg *pkg.Config,
+	servers httpServers,
+	spreadsheetChanged chan<- struct{}) *pkg.DriveNotificationReceiver {
+	config := cfg.GetWatchConfiguration()
+	if !config.Enabled {
+		return nil
+	}
+
+	receiver := pkg.NewDriveNotificationReceiver(config.Token, config.Debounce, func() {
+		select {
+		case spreadsheetChanged <- struct{}{}:
+		default:
+		}
+	}, cfg.GetLogger())
+
+	servers.handle(config.Listen, config.Path, receiver)
+
+	return receiver
+}
+
+// driveWatchers One watcher for each spreadsheet.
+type driveWatchers []*pkg.DriveWatcher
+
+// Active Polling can only slow down if every spreadsheet is being watched.
+func (watchers driveWatchers) Active() bool {
+	for _, watcher := range watchers {
+		if !watcher.Active() {
+			return false
+		}
+	}
+
+	return len(watchers) > 0
+}
+
+func (watchers driveWatchers) Stop() {
+	for _, watcher := range watchers {
+		watcher.Stop()
+	}
+}
+
+// startDriveWatch Start receiving Drive push notifications for the spreadsheets.  Returns nil if
+// notifications aren't enabled or can't be set up for all of them, in which case we just poll.
+func startDriveWatch(
+	ctx context.Context,
+	cfg *pkg.Config,
+	receiver *pkg.DriveNotificationReceiver) driveWatchers {
+	if receiver == nil {
+		return nil
+	}
+
+	logger := cfg.GetLogger()
+
+	_, client, err := pkg.AccessGoogleClient(cfg, pkg.ProfileWatch)
+	if err != nil {
+		logger.Warn("Unable to watch the spreadsheet, polling instead", "error", err)
+
+		return nil
+	}
+
+	driveService, err := drive.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		logger.Warn("Unable to watch the spreadsheet, polling instead", "error", err)
+
+		return nil
+	}
+
+	var watchers driveWatchers
+
+	for _, source := range cfg.GetSpreadsheetSources() {
+		watcher := pkg.NewDriveWatcher(driveService, source.SpreadsheetID, cfg.GetWatchConfiguration(), receiver,
+			logger)
+		if err = watcher.Start(); err != nil {
+			logger.Warn("Unable to watch the spreadsheet, polling instead", "spreadsheet", source.Name, "error", err)
+			watchers.Stop()
+
+			return nil
+		}
+
+		watchers = append(watchers, watcher)
+	}
+
+	for _, watcher := range watchers {
+		go watcher.RenewUntilDone(ctx)
+	}
+
+	return watchers
+}
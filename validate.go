@@ -0,0 +1,117 @@
+// Copyright 2024 Peter Schwaller (peter@schwaller.org)
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"schwaller.org/go-brown-sports/pkg"
+	"slices"
+)
+
+const outputSARIF = "sarif"
+
+// severityRanks Lower is more serious.
+var severityRanks = map[pkg.Severity]int{pkg.SeverityError: 0, pkg.SeverityWarning: 1, pkg.SeverityInfo: 2}
+
+// validateReport The output of validate -output json.
+type validateReport struct {
+	Events         int                 `json:"events"`
+	Findings       []pkg.Diagnostic    `json:"findings"`
+	UnknownWorkers []pkg.UnknownWorker `json:"unknownWorkers"`
+}
+
+// runValidate Read the spreadsheet and report its problems, without touching the calendar.  Month
+// tabs the sync skips are checked too.
+func runValidate(args []string) int {
+	flags, options := newFlagSet("validate", outputText, outputJSON, outputSARIF)
+	minimumSeverity := flags.String("severity", string(pkg.SeverityInfo),
+		"only report findings at least this serious: error, warning or info")
+
+	if exitCode, ok := options.parse(flags, args); !ok {
+		return exitCode
+	}
+
+	minimumRank, ok := severityRanks[pkg.Severity(*minimumSeverity)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Invalid -severity %q, expected error, warning or info\n", *minimumSeverity)
+
+		return exitUsage
+	}
+
+	ctx, client, err := pkg.AccessGoogleClient(options.config, pkg.ProfileReadOnly)
+	if err != nil {
+		return failure("Unable to create Google client", "error", err)
+	}
+
+	sheetService, err := pkg.AccessSpreadsheet(ctx, client)
+	if err != nil {
+		return failure("Unable to access spreadsheet", "error", err)
+	}
+
+	// The findings are the output, so there's no need to log them as well.
+	diagnostics := pkg.NewDiagnostics(quietLogger())
+
+	sportingEvents, err := pkg.ValidateSpreadsheet(ctx, options.config, sheetService, diagnostics)
+	if err != nil {
+		return failure("Unable to read the spreadsheet", "error", err)
+	}
+
+	report := validateReport{
+		Events:         len(sportingEvents),
+		Findings:       []pkg.Diagnostic{},
+		UnknownWorkers: diagnostics.GetUnknownWorkers(),
+	}
+
+	for _, finding := range diagnostics.Findings() {
+		if severityRanks[finding.Severity] <= minimumRank {
+			report.Findings = append(report.Findings, finding)
+		}
+	}
+
+	exitCode := exitOK
+
+	switch options.output {
+	case outputJSON:
+		exitCode = printJSON(report)
+	case outputSARIF:
+		if err = pkg.WriteSARIF(os.Stdout, options.config.GetSpreadsheetSources(), report.Findings); err != nil {
+			exitCode = failure("Unable to write output", "error", err)
+		}
+	default:
+		printValidateReport(report)
+	}
+
+	if exitCode != exitOK {
+		return exitCode
+	}
+
+	// Info findings are just for the record, so they don't need fixing.
+	if slices.ContainsFunc(report.Findings, func(finding pkg.Diagnostic) bool {
+		return finding.Severity != pkg.SeverityInfo
+	}) {
+		return exitFindings
+	}
+
+	return exitOK
+}
+
+func printValidateReport(report validateReport) {
+	counts := make(map[pkg.Severity]int)
+
+	for _, finding := range report.Findings {
+		printDiagnostic(finding)
+		counts[finding.Severity]++
+	}
+
+	fmt.Printf("Checked %d events: %d errors, %d warnings, %d info.\n",
+		report.Events, counts[pkg.SeverityError], counts[pkg.SeverityWarning], counts[pkg.SeverityInfo])
+}
+
+func printDiagnostic(finding pkg.Diagnostic) {
+	fmt.Printf("%-7s  %s  %s [%s]\n", finding.Severity, finding.Location(), finding.Message, finding.Rule)
+
+	if finding.Fix != "" {
+		fmt.Printf("         Fix: %s\n", finding.Fix)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 Peter Schwaller (peter@schwaller.org)
+
+package main
+
+import (
+	"fmt"
+	"schwaller.org/go-brown-sports/pkg"
+)
+
+// workersReport The output of workers -output json.
+type workersReport struct {
+	Workers        []workerEntry       `json:"workers"`
+	UnknownWorkers []pkg.UnknownWorker `json:"unknownWorkers"`
+	Problems       []pkg.Diagnostic    `json:"problems"`
+}
+
+type workerEntry struct {
+	pkg.Worker
+	Assignments int `json:"assignments"` // Across every month on the spreadsheet
+}
+
+func runWorkers(args []string) int {
+	flags, options := newFlagSet("workers")
+	if exitCode, ok := options.parse(flags, args); !ok {
+		return exitCode
+	}
+
+	ctx, client, err := pkg.AccessGoogleClient(options.config, pkg.ProfileReadOnly)
+	if err != nil {
+		return failure("Unable to create Google client", "error", err)
+	}
+
+	spreadsheet, err := loadSpreadsheet(ctx, options.config, quietLogger(), client)
+	if err != nil {
+		return failure("Unable to read the spreadsheet", "error", err)
+	}
+
+	// A worker can be on the schedule under their full name or first name, so count by email address.
+	assignmentCounts := make(map[string]int)
+
+	for _, sportingEvent := range spreadsheet.events {
+		for _, assignment := range sportingEvent.Assignments {
+			if assignment.Email != "" {
+				assignmentCounts[assignment.Email]++
+			}
+		}
+	}
+
+	report := workersReport{
+		Workers:        []workerEntry{},
+		UnknownWorkers: spreadsheet.diagnostics.GetUnknownWorkers(),
+		Problems:       []pkg.Diagnostic{},
+	}
+
+	for _, worker := range spreadsheet.workers {
+		report.Workers = append(report.Workers, workerEntry{Worker: worker, Assignments: assignmentCounts[worker.Email]})
+	}
+
+	for _, problem := range spreadsheet.diagnostics.Problems {
+		if problem.Tab == pkg.WorkerContactInfoTab {
+			report.Problems = append(report.Problems, problem)
+		}
+	}
+
+	if options.output == outputJSON {
+		if exitCode := printJSON(report); exitCode != exitOK {
+			return exitCode
+		}
+	} else {
+		printWorkersReport(report)
+	}
+
+	if len(report.UnknownWorkers) > 0 || len(report.Problems) > 0 {
+		return exitFindings
+	}
+
+	return exitOK
+}
+
+func printWorkersReport(report workersReport) {
+	for _, worker := range report.Workers {
+		fmt.Printf("%-4d  %-30s  %-40s  %d assignments\n", worker.Row, worker.Name, worker.Email, worker.Assignments)
+	}
+
+	for _, problem := range report.Problems {
+		printDiagnostic(problem)
+	}
+
+	if len(report.UnknownWorkers) > 0 {
+		fmt.Println("\nNames on the schedule that aren't in the Worker Contact Info tab:")
+
+		for _, unknownWorker := range report.UnknownWorkers {
+			fmt.Printf("    %s (%d assignments)\n", unknownWorker.Name, unknownWorker.Count)
+		}
+	}
+}